@@ -2,17 +2,38 @@ CREATE TABLE IF NOT EXISTS banners (
     id BIGSERIAL PRIMARY KEY,
     content JSONB NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Растёт при каждом изменении баннера, используется для оптимистичной блокировки
+    revision BIGINT NOT NULL DEFAULT 1,
+    -- Окно показа пользователям, NULL — граница не задана
+    active_from TIMESTAMP WITH TIME ZONE,
+    active_until TIMESTAMP WITH TIME ZONE,
+    -- Ограничение частоты показов одному пользователю {"count": N, "window": секунды}, NULL — без ограничения
+    frequency_cap JSONB,
+    -- Переводы content по языкам {"en": {...}, "pt-br": {...}}, NULL — переводов нет
+    localized_content JSONB,
+    CHECK (active_from IS NULL OR active_until IS NULL OR active_from < active_until)
 );
 
 CREATE TABLE IF NOT EXISTS banners_history (
     banner_id BIGINT NOT NULL,
     content JSONB NOT NULL,
+    localized_content JSONB,
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (banner_id, updated_at),
     FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
 );
 
+-- Варианты содержимого баннера для A/B-тестов. Пока вариантов нет, показывается banners.content
+CREATE TABLE IF NOT EXISTS banner_variants (
+    banner_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    content JSONB NOT NULL,
+    weight INT NOT NULL CHECK (weight >= 0),
+    PRIMARY KEY (banner_id, name),
+    FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS banner_mappings (
     banner_id BIGINT NOT NULL,
     feature_id BIGINT NOT NULL,
@@ -46,4 +67,64 @@ $$ LANGUAGE plpgsql;
 
 CREATE TRIGGER keep_last_3_versions_trigger
 AFTER INSERT ON banners_history
-FOR EACH ROW EXECUTE FUNCTION keep_last_3_versions();
\ No newline at end of file
+FOR EACH ROW EXECUTE FUNCTION keep_last_3_versions();
+
+-- Статусы отложенных задач (удаление баннеров)
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Задачи на удаление, которые не удалось выполнить за все попытки
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL,
+    task JSONB NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    attempts INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Очередь задач в Postgres, альтернатива RabbitMQ
+CREATE TABLE IF NOT EXISTS queue_messages (
+    id BIGSERIAL PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    body BYTEA NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS queue_messages_queue_id_idx ON queue_messages (queue, id);
+
+-- Изменения баннеров, запланированные на apply_at. Планировщик захватывает их так же,
+-- как сообщения queue_messages, через locked_until
+CREATE TABLE IF NOT EXISTS scheduled_changes (
+    id BIGSERIAL PRIMARY KEY,
+    banner_id BIGINT NOT NULL,
+    change JSONB NOT NULL,
+    apply_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_changes_pending_idx ON scheduled_changes (apply_at) WHERE status = 'pending';
+
+-- Показы и клики по баннерам. Внешнего ключа на banners нет: события пишутся пачками через COPY,
+-- и удалённый между приёмом и записью баннер не должен ронять всю пачку
+CREATE TABLE IF NOT EXISTS banner_events (
+    banner_id BIGINT NOT NULL,
+    variant VARCHAR(64) NOT NULL,
+    tag_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS banner_events_banner_id_created_at_idx ON banner_events (banner_id, created_at);
@@ -0,0 +1,9 @@
+-- Задачи на удаление, которые не удалось выполнить за все попытки
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL,
+    task JSONB NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    attempts INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
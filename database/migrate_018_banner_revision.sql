@@ -0,0 +1,3 @@
+-- Ревизия баннера для оптимистичной блокировки. Файлы migrate_* выполняются после create.sql
+-- при создании базы и вручную (psql -f) на базе, созданной раньше
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS revision BIGINT NOT NULL DEFAULT 1;
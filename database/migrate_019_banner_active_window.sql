@@ -0,0 +1,19 @@
+-- Окно показа баннера пользователям
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS active_from TIMESTAMP WITH TIME ZONE;
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS active_until TIMESTAMP WITH TIME ZONE;
+
+-- В новой базе такую же проверку уже создал create.sql, поэтому ищется проверка с тем же условием
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM pg_constraint
+        WHERE conrelid = 'banners'::regclass
+        AND contype = 'c'
+        AND pg_get_constraintdef(oid) LIKE '%active_from < active_until%'
+    ) THEN
+        ALTER TABLE banners ADD CONSTRAINT banners_active_window_check
+            CHECK (active_from IS NULL OR active_until IS NULL OR active_from < active_until);
+    END IF;
+END;
+$$;
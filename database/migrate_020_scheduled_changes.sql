@@ -0,0 +1,16 @@
+-- Изменения баннеров, запланированные на apply_at. Планировщик захватывает их так же,
+-- как сообщения queue_messages, через locked_until
+CREATE TABLE IF NOT EXISTS scheduled_changes (
+    id BIGSERIAL PRIMARY KEY,
+    banner_id BIGINT NOT NULL,
+    change JSONB NOT NULL,
+    apply_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_changes_pending_idx ON scheduled_changes (apply_at) WHERE status = 'pending';
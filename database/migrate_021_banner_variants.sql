@@ -0,0 +1,9 @@
+-- Варианты содержимого баннера для A/B-тестов. Пока вариантов нет, показывается banners.content
+CREATE TABLE IF NOT EXISTS banner_variants (
+    banner_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    content JSONB NOT NULL,
+    weight INT NOT NULL CHECK (weight >= 0),
+    PRIMARY KEY (banner_id, name),
+    FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE
+);
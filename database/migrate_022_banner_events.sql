@@ -0,0 +1,12 @@
+-- Показы и клики по баннерам. Внешнего ключа на banners нет: события пишутся пачками через COPY,
+-- и удалённый между приёмом и записью баннер не должен ронять всю пачку
+CREATE TABLE IF NOT EXISTS banner_events (
+    banner_id BIGINT NOT NULL,
+    variant VARCHAR(64) NOT NULL,
+    tag_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS banner_events_banner_id_created_at_idx ON banner_events (banner_id, created_at);
@@ -0,0 +1,2 @@
+-- Ограничение частоты показов баннера одному пользователю
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS frequency_cap JSONB;
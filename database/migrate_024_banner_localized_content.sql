@@ -0,0 +1,3 @@
+-- Переводы содержимого баннера и их история
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS localized_content JSONB;
+ALTER TABLE banners_history ADD COLUMN IF NOT EXISTS localized_content JSONB;
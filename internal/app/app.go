@@ -6,10 +6,13 @@ import (
 	"backend-trainee-assignment-2024/internal/repo"
 	"backend-trainee-assignment-2024/internal/services/auth"
 	"backend-trainee-assignment-2024/internal/services/banner"
+	"backend-trainee-assignment-2024/internal/storage/memory"
 	"backend-trainee-assignment-2024/internal/storage/postgres"
 	"backend-trainee-assignment-2024/internal/storage/rabbitmq"
 	"backend-trainee-assignment-2024/internal/storage/redis"
 	"backend-trainee-assignment-2024/internal/transport"
+	"context"
+	"expvar"
 	"fmt"
 	"os"
 	"os/signal"
@@ -41,22 +44,50 @@ func Run() {
 
 	// Инициализация репозиториев
 	logger.Info("initializing repos...")
-	repo := repo.NewBannerRepository(postgres)
+	bannerRepo := repo.NewBannerRepository(postgres)
+	jobRepo := repo.NewJobRepository(postgres)
+	deadLetterRepo := repo.NewDeadLetterRepository(postgres)
+	scheduledChangeRepo := repo.NewScheduledChangeRepository(postgres)
+	eventRepo := repo.NewEventRepository(postgres)
 
-	cache := redis.NewRedis(cfg.Redis)
+	cache, err := newCache(cfg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("app.Run failed to init cache: %s\n", err.Error()))
+		os.Exit(1)
+	}
 	defer cache.Close()
 
-	queue, err := rabbitmq.NewRabbitMQQueue(cfg.RabbitMQ)
-	defer queue.Close()
+	// Пока кэш недоступен, запросы идут сразу в базу
+	cacheBreaker := banner.NewCacheBreaker(cache, cfg.Cache, logger)
+	defer cacheBreaker.Stop()
+
+	queue, err := newQueue(cfg, postgres, logger)
 	if err != nil {
 		logger.Error(fmt.Sprintf("app.Run failed to init queue: %s\n", err.Error()))
 		os.Exit(1)
 	}
+	defer queue.Close()
 
 	// Инициализация сервисов
-	bannerService := banner.NewBannerService(cfg.BannerService, repo, cache, queue, logger)
+	bannerService := banner.NewBannerService(cfg.BannerService, bannerRepo, jobRepo, deadLetterRepo, scheduledChangeRepo, eventRepo, cacheBreaker, queue, logger)
 	defer bannerService.Shutdown()
 
+	// Счётчики локального кэша доступны админам в GET /debug/vars
+	expvar.Publish("banner_local_cache", expvar.Func(func() any {
+		return bannerService.CacheStats()
+	}))
+	expvar.Publish("banner_events", expvar.Func(func() any {
+		return bannerService.EventStats()
+	}))
+	expvar.Publish("banner_cache_breaker", expvar.Func(func() any {
+		return cacheBreaker.Stats()
+	}))
+
+	// Сброс кэша по изменениям баннеров с других инстансов
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	go bannerService.ListenBannerChanges(listenCtx, bannerRepo, cfg.Postgres.ListenReconnectDelay)
+
 	authService := auth.NewAuthService(cfg.AuthService.SecretKey)
 
 	done := make(chan os.Signal, 1)
@@ -81,3 +112,32 @@ func Run() {
 
 	logger.Info("app.Run server ended")
 }
+
+func newQueue(cfg *config.Config, db *postgres.Postgres, logger logger.Logger) (banner.Queue, error) {
+	switch cfg.Queue.Driver {
+	case config.QueueDriverRabbitMQ:
+		return rabbitmq.NewRabbitMQQueue(cfg.RabbitMQ)
+	case config.QueueDriverPostgres:
+		return postgres.NewPostgresQueue(db, cfg.Queue, logger), nil
+	case config.QueueDriverMemory:
+		return memory.NewQueue(cfg.Queue.BufferSize), nil
+	default:
+		return nil, fmt.Errorf("unknown queue driver: %s", cfg.Queue.Driver)
+	}
+}
+
+type closableCache interface {
+	banner.Cache
+	Close() error
+}
+
+func newCache(cfg *config.Config) (closableCache, error) {
+	switch cfg.Cache.Driver {
+	case config.CacheDriverRedis:
+		return redis.NewRedis(cfg.Redis), nil
+	case config.CacheDriverMemory:
+		return memory.NewCache(cfg.Cache.CleanupInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver: %s", cfg.Cache.Driver)
+	}
+}
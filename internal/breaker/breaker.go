@@ -0,0 +1,172 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	// Вызовы проходят, ошибки подряд считаются
+	Closed State = iota
+	// Вызовы отклоняются сразу с ErrOpen, пока не истечёт openTimeout
+	Open
+	// Пропускается один пробный вызов, по его результату breaker закрывается или снова открывается
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Circuit breaker: после maxFailures ошибок подряд перестаёт пускать вызовы к зависимости
+// на openTimeout, чтобы не добивать её и не ждать таймаутов на каждом запросе
+type Breaker struct {
+	mu          sync.Mutex
+	maxFailures int
+	openTimeout time.Duration
+	state       State
+	failures    int
+	openedAt    time.Time
+	probing     bool
+
+	// Какие ошибки считаются отказом зависимости, по умолчанию любые
+	IsFailure func(err error) bool
+	// Вызывается при каждой смене состояния, не должен блокироваться
+	OnStateChange func(from, to State)
+}
+
+func New(maxFailures int, openTimeout time.Duration) *Breaker {
+	return &Breaker{
+		maxFailures: maxFailures,
+		openTimeout: openTimeout,
+	}
+}
+
+// Выполнение fn через breaker. Если breaker открыт, fn не вызывается и возвращается ErrOpen
+func (b *Breaker) Do(fn func() error) error {
+	if !b.allow() {
+		return ErrOpen
+	}
+
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// Пробный вызов вне потока запросов, например из фоновой проверки зависимости.
+// Выполняется, только если breaker не закрыт: при успехе закрывает его, при ошибке продлевает открытое состояние
+func (b *Breaker) Probe(fn func() error) error {
+	if b.State() == Closed {
+		return nil
+	}
+
+	err := fn()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil
+	if failed && b.IsFailure != nil {
+		failed = b.IsFailure(err)
+	}
+	if failed {
+		b.open()
+		return err
+	}
+	b.failures = 0
+	b.setState(Closed)
+	return nil
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return true
+	case HalfOpen:
+		// Пробный вызов уже выполняется, остальные ждут его результата
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil
+	if failed && b.IsFailure != nil {
+		failed = b.IsFailure(err)
+	}
+
+	if b.state == HalfOpen {
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.failures = 0
+			b.setState(Closed)
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == Closed && b.failures >= b.maxFailures {
+		b.open()
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, state)
+	}
+}
@@ -0,0 +1,111 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errFailed = errors.New("dependency failed")
+
+func TestBreaker(t *testing.T) {
+	var transitions []string
+	b := New(2, 50*time.Millisecond)
+	b.OnStateChange = func(from, to State) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}
+
+	fail := func() error { return errFailed }
+	ok := func() error { return nil }
+
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.NoError(t, b.Do(ok))
+	assert.Equal(t, Closed, b.State(), "success resets the failure counter")
+
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.Equal(t, Open, b.State())
+
+	called := false
+	err := b.Do(func() error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.False(t, called)
+
+	// После openTimeout пропускается пробный вызов
+	time.Sleep(60 * time.Millisecond)
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.Equal(t, Open, b.State())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, b.Do(ok))
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+func TestBreaker_IsFailure(t *testing.T) {
+	errNotFound := errors.New("not found")
+	b := New(1, time.Minute)
+	b.IsFailure = func(err error) bool {
+		return !errors.Is(err, errNotFound)
+	}
+
+	assert.ErrorIs(t, b.Do(func() error { return errNotFound }), errNotFound)
+	assert.Equal(t, Closed, b.State())
+
+	assert.ErrorIs(t, b.Do(func() error { return errFailed }), errFailed)
+	assert.Equal(t, Open, b.State())
+}
+
+func TestBreaker_SingleProbe(t *testing.T) {
+	b := New(1, 10*time.Millisecond)
+	_ = b.Do(func() error { return errFailed })
+	time.Sleep(20 * time.Millisecond)
+
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(func() error {
+			<-release
+			return nil
+		})
+	}()
+
+	assert.Eventually(t, func() bool { return b.State() == HalfOpen }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, b.Do(func() error { return nil }), ErrOpen)
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_Probe(t *testing.T) {
+	b := New(1, time.Minute)
+
+	called := false
+	assert.NoError(t, b.Probe(func() error {
+		called = true
+		return nil
+	}))
+	assert.False(t, called, "closed breaker is not probed")
+
+	_ = b.Do(func() error { return errFailed })
+	assert.Equal(t, Open, b.State())
+
+	assert.ErrorIs(t, b.Probe(func() error { return errFailed }), errFailed)
+	assert.Equal(t, Open, b.State())
+
+	assert.NoError(t, b.Probe(func() error { return nil }))
+	assert.Equal(t, Closed, b.State())
+}
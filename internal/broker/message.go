@@ -0,0 +1,31 @@
+package broker
+
+// Сообщение из очереди. Должно быть подтверждено (Ack) после успешной обработки,
+// иначе брокер доставит его повторно
+type Message struct {
+	Body []byte
+	ack  func() error
+	nack func(requeue bool) error
+}
+
+func NewMessage(body []byte, ack func() error, nack func(requeue bool) error) Message {
+	return Message{
+		Body: body,
+		ack:  ack,
+		nack: nack,
+	}
+}
+
+func (m Message) Ack() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack()
+}
+
+func (m Message) Nack(requeue bool) error {
+	if m.nack == nil {
+		return nil
+	}
+	return m.nack(requeue)
+}
@@ -16,6 +16,8 @@ type Config struct {
 	BannerService `yaml:"banner_service"`
 	Redis         `yaml:"redis"`
 	RabbitMQ      `yaml:"rabbitmq"`
+	Queue         `yaml:"queue"`
+	Cache         `yaml:"cache"`
 }
 
 type Logger struct {
@@ -31,13 +33,14 @@ type HTTPServer struct {
 }
 
 type Postgres struct {
-	MaxPoolSize int           `yaml:"max_pool_size" env-default:"2"`
-	ConnTimeout time.Duration `yaml:"conn_timeout" env-default:"3s"`
-	User        string        `yaml:"user" env:"DB_USER"`
-	Password    string        `yaml:"password" env:"DB_PASSWORD"`
-	Host        string        `yaml:"host"`
-	Port        int           `yaml:"port"`
-	DBName      string        `yaml:"db_name" env:"DB_NAME"`
+	MaxPoolSize          int           `yaml:"max_pool_size" env-default:"2"`
+	ConnTimeout          time.Duration `yaml:"conn_timeout" env-default:"3s"`
+	User                 string        `yaml:"user" env:"DB_USER"`
+	Password             string        `yaml:"password" env:"DB_PASSWORD"`
+	Host                 string        `yaml:"host"`
+	Port                 int           `yaml:"port"`
+	DBName               string        `yaml:"db_name" env:"DB_NAME"`
+	ListenReconnectDelay time.Duration `yaml:"listen_reconnect_delay" env-default:"5s"`
 }
 
 type AuthService struct {
@@ -45,9 +48,29 @@ type AuthService struct {
 }
 
 type BannerService struct {
-	CacheTTL         time.Duration `yaml:"cached_ttl" env-default:"300s"`
-	DeleteWorkersNum int           `yaml:"delete_workers_num" env-default:"1"`
-	QueueName        string        `yaml:"queue_name"`
+	CacheTTL                     time.Duration `yaml:"cached_ttl" env-default:"300s"`
+	NotFoundTTL                  time.Duration `yaml:"not_found_ttl" env-default:"10s"`
+	DeleteWorkersNum             int           `yaml:"delete_workers_num" env-default:"1"`
+	DeleteBatchSize              int           `yaml:"delete_batch_size" env-default:"1000"`
+	DeleteAttempts               int           `yaml:"delete_attempts" env-default:"5"`
+	RetryBaseDelay               time.Duration `yaml:"retry_base_delay" env-default:"1s"`
+	RetryMaxDelay                time.Duration `yaml:"retry_max_delay" env-default:"1m"`
+	QueueName                    string        `yaml:"queue_name"`
+	LocalCacheSize               int           `yaml:"local_cache_size" env-default:"10000"`
+	LocalCacheTTL                time.Duration `yaml:"local_cache_ttl" env-default:"10s"`
+	SnapshotEnabled              bool          `yaml:"snapshot_enabled" env-default:"false"`
+	SnapshotRefreshInterval      time.Duration `yaml:"snapshot_refresh_interval" env-default:"1m"`
+	LastKnownGoodPath            string        `yaml:"last_known_good_path"`
+	LastKnownGoodFlushInterval   time.Duration `yaml:"last_known_good_flush_interval" env-default:"10s"`
+	DBBreakerMaxFailures         int           `yaml:"db_breaker_max_failures" env-default:"5"`
+	DBBreakerOpenTimeout         time.Duration `yaml:"db_breaker_open_timeout" env-default:"10s"`
+	ScheduledChangesPollInterval time.Duration `yaml:"scheduled_changes_poll_interval" env-default:"1s"`
+	ScheduledChangesLockTimeout  time.Duration `yaml:"scheduled_changes_lock_timeout" env-default:"1m"`
+	ScheduledChangesBatchSize    int           `yaml:"scheduled_changes_batch_size" env-default:"100"`
+	EventsFlushInterval          time.Duration `yaml:"events_flush_interval" env-default:"1s"`
+	EventsBatchSize              int           `yaml:"events_batch_size" env-default:"1000"`
+	EventsBufferSize             int           `yaml:"events_buffer_size" env-default:"100000"`
+	LocaleFallback               []string      `yaml:"locale_fallback" env-default:"en"`
 }
 
 type Redis struct {
@@ -55,14 +78,44 @@ type Redis struct {
 	Port        int           `yaml:"port"`
 	Password    string        `yaml:"password" env:"REDIS_PASSWORD"`
 	ConnTimeout time.Duration `yaml:"conn_timeout" env-default:"3s"`
+	OpTimeout   time.Duration `yaml:"op_timeout" env-default:"100ms"`
 }
 
 type RabbitMQ struct {
-	Address  string `yaml:"address"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user" env:"RABBITMQ_DEFAULT_USER"`
-	Password string `yaml:"password" env:"RABBITMQ_DEFAULT_PASS"`
-	Name     string `yaml:"name"`
+	Address        string        `yaml:"address"`
+	Port           int           `yaml:"port"`
+	User           string        `yaml:"user" env:"RABBITMQ_DEFAULT_USER"`
+	Password       string        `yaml:"password" env:"RABBITMQ_DEFAULT_PASS"`
+	Name           string        `yaml:"name"`
+	PrefetchCount  int           `yaml:"prefetch_count" env-default:"50"`
+	ReconnectDelay time.Duration `yaml:"reconnect_delay" env-default:"5s"`
+}
+
+const (
+	QueueDriverRabbitMQ = "rabbitmq"
+	QueueDriverPostgres = "postgres"
+	QueueDriverMemory   = "memory"
+)
+
+// Выбор реализации очереди для отложенных задач
+type Queue struct {
+	Driver            string        `yaml:"driver" env-default:"rabbitmq"`
+	PollInterval      time.Duration `yaml:"poll_interval" env-default:"1s"`
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout" env-default:"5m"`
+	BufferSize        int           `yaml:"buffer_size" env-default:"1000"`
+}
+
+const (
+	CacheDriverRedis  = "redis"
+	CacheDriverMemory = "memory"
+)
+
+// Выбор реализации кэша баннеров
+type Cache struct {
+	Driver               string        `yaml:"driver" env-default:"redis"`
+	CleanupInterval      time.Duration `yaml:"cleanup_interval" env-default:"1m"`
+	BreakerMaxFailures   int           `yaml:"breaker_max_failures" env-default:"5"`
+	BreakerProbeInterval time.Duration `yaml:"breaker_probe_interval" env-default:"1s"`
 }
 
 func Init() (*Config, error) {
@@ -84,5 +137,10 @@ func Init() (*Config, error) {
 		return nil, fmt.Errorf("config.Init cannot read config: %s", err)
 	}
 
+	// Воркер удаляет баннеры пачками, пока пачка не окажется неполной: с нулевым размером он не остановится
+	if cfg.BannerService.DeleteBatchSize <= 0 {
+		return nil, fmt.Errorf("config.Init banner_service.delete_batch_size must be positive, got %d", cfg.BannerService.DeleteBatchSize)
+	}
+
 	return &cfg, nil
 }
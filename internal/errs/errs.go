@@ -14,8 +14,17 @@ var (
 )
 
 var (
-	ErrNotFound        = errors.New("not found")
-	ErrUniqueViolation = errors.New("unique violation")
+	ErrInvalidVariants = errors.New("invalid variants")
+)
+
+var (
+	ErrInvalidPlaceholder = errors.New("invalid placeholder")
+)
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrUniqueViolation  = errors.New("unique violation")
+	ErrRevisionMismatch = errors.New("revision mismatch")
 )
 
 var (
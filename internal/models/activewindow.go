@@ -0,0 +1,39 @@
+package models
+
+import (
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"time"
+)
+
+// Окно, в котором баннер показывается пользователям. Границы — unix-время, любая может отсутствовать,
+// active_from входит в окно, active_until — нет
+type ActiveWindow struct {
+	ActiveFrom  n.NullInt64 `json:"active_from"`
+	ActiveUntil n.NullInt64 `json:"active_until"`
+}
+
+func (w ActiveWindow) Contains(now time.Time) bool {
+	if w.ActiveFrom.Valid && now.Unix() < w.ActiveFrom.Int64 {
+		return false
+	}
+	if w.ActiveUntil.Valid && now.Unix() >= w.ActiveUntil.Int64 {
+		return false
+	}
+	return true
+}
+
+// Ближайшая будущая граница окна, на которой видимость баннера сменится
+func (w ActiveWindow) NextBoundary(now time.Time) (time.Time, bool) {
+	if w.ActiveFrom.Valid && now.Unix() < w.ActiveFrom.Int64 {
+		return time.Unix(w.ActiveFrom.Int64, 0), true
+	}
+	if w.ActiveUntil.Valid && now.Unix() < w.ActiveUntil.Int64 {
+		return time.Unix(w.ActiveUntil.Int64, 0), true
+	}
+	return time.Time{}, false
+}
+
+// Окно пустое, если начало не раньше конца
+func (w ActiveWindow) Empty() bool {
+	return w.ActiveFrom.Valid && w.ActiveUntil.Valid && w.ActiveFrom.Int64 >= w.ActiveUntil.Int64
+}
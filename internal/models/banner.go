@@ -4,16 +4,27 @@ import (
 	"backend-trainee-assignment-2024/internal/errs"
 	"database/sql/driver"
 	"encoding/json"
+	"time"
 )
 
 type Banner struct {
-	ID        int64           `json:"id"`
-	Content   json.RawMessage `json:"content"`
-	IsActive  bool            `json:"is_active"`
-	CreatedAt UnixTime        `json:"created_at"`
-	UpdatedAt UnixTime        `json:"updated_at"`
-	FeatureID int64           `json:"feature_id"`
-	TagIds    []int64         `json:"tag_ids"`
+	ID               int64            `json:"id"`
+	Content          json.RawMessage  `json:"content"`
+	IsActive         bool             `json:"is_active"`
+	CreatedAt        UnixTime         `json:"created_at"`
+	UpdatedAt        UnixTime         `json:"updated_at"`
+	FeatureID        int64            `json:"feature_id"`
+	TagIds           []int64          `json:"tag_ids"`
+	Revision         int64            `json:"revision"`
+	Variants         []BannerVariant  `json:"variants,omitempty"`
+	FrequencyCap     *FrequencyCap    `json:"frequency_cap,omitempty"`
+	LocalizedContent LocalizedContent `json:"localized_content,omitempty"`
+	ActiveWindow
+}
+
+// Видит ли баннер пользователь: баннер включён и момент попадает в окно показа
+func (b Banner) VisibleAt(now time.Time) bool {
+	return b.IsActive && b.Contains(now)
 }
 
 func (b *Banner) Scan(value interface{}) error {
@@ -0,0 +1,9 @@
+package models
+
+// Изменение баннера, о котором репозиторий оповещает все инстансы сервиса.
+// Содержит фичи и теги баннера и до, и после изменения
+type BannerChange struct {
+	BannerID   int64   `json:"banner_id"`
+	FeatureIDs []int64 `json:"feature_ids"`
+	TagIDs     []int64 `json:"tag_ids"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"encoding/json"
+)
+
+// Новый баннер в том же виде, что и тело POST /banner
+type BannerInput struct {
+	TagIDs           []int64          `json:"tag_ids"`
+	FeatureID        int64            `json:"feature_id"`
+	Content          json.RawMessage  `json:"content"`
+	IsActive         bool             `json:"is_active"`
+	Variants         []BannerVariant  `json:"variants"`
+	FrequencyCap     *FrequencyCap    `json:"frequency_cap"`
+	LocalizedContent LocalizedContent `json:"localized_content"`
+	ActiveWindow
+}
+
+// Частичное изменение баннера в том же виде, что и тело PATCH /banner/{id}
+type BannerPatch struct {
+	TagIDs    []int64         `json:"tag_ids,omitempty"`
+	FeatureID n.NullInt64     `json:"feature_id"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsActive  n.NullBool      `json:"is_active"`
+	// Заменяют прежние варианты целиком, пустой список удаляет все варианты
+	Variants []BannerVariant `json:"variants,omitempty"`
+	// Нулевое ограничение снимает его
+	FrequencyCap *FrequencyCap `json:"frequency_cap,omitempty"`
+	// Переводы сливаются с сохранёнными, null удаляет перевод
+	LocalizedContent LocalizedContent `json:"localized_content,omitempty"`
+	// Нулевая граница снимает её
+	ActiveWindow
+}
+
+// Изменение, в котором не передано ни одного поля
+func (p BannerPatch) IsZero() bool {
+	return len(p.TagIDs) == 0 && !p.FeatureID.Valid && p.Content == nil && !p.IsActive.Valid &&
+		p.Variants == nil && p.FrequencyCap == nil && p.LocalizedContent == nil &&
+		!p.ActiveFrom.Valid && !p.ActiveUntil.Valid
+}
@@ -0,0 +1,24 @@
+package models
+
+// Пара тег-фича, по которой пользователь получает баннер
+type BannerKey struct {
+	TagID     int64 `json:"tag_id"`
+	FeatureID int64 `json:"feature_id"`
+}
+
+type BannerStatus string
+
+const (
+	BannerFound    BannerStatus = "ok"
+	BannerNotFound BannerStatus = "not_found"
+	BannerInactive BannerStatus = "inactive"
+	// База недоступна, а в последнем сохранённом состоянии пары нет
+	BannerUnavailable BannerStatus = "unavailable"
+)
+
+// Результат поиска баннера по одной паре тег-фича при пакетной выдаче
+type BannerLookup struct {
+	Key    BannerKey
+	Status BannerStatus
+	Banner Banner
+}
@@ -0,0 +1,12 @@
+package models
+
+import "encoding/json"
+
+type DeadLetter struct {
+	ID        int64           `json:"id"`
+	JobID     int64           `json:"job_id"`
+	Task      json.RawMessage `json:"task"`
+	Error     string          `json:"error"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt UnixTime        `json:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type EventType string
+
+const (
+	EventImpression EventType = "impression"
+	EventClick      EventType = "click"
+)
+
+// Показ или клик по баннеру. Пользователь берётся из токена, время — из момента приёма события
+type Event struct {
+	Type      EventType `json:"type"`
+	BannerID  int64     `json:"banner_id"`
+	Variant   string    `json:"variant"`
+	TagID     int64     `json:"tag_id"`
+	UserID    int64     `json:"-"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Число показов и кликов за сутки (UTC)
+type EventCounts struct {
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+}
+
+type VariantStats struct {
+	Variant string `json:"variant"`
+	EventCounts
+}
+
+type DailyStats struct {
+	Day string `json:"day"`
+	EventCounts
+	Variants []VariantStats `json:"variants"`
+}
+
+// Заполнение CTR по уже посчитанным показам и кликам
+func (c *EventCounts) UpdateCTR() {
+	c.CTR = 0
+	if c.Impressions > 0 {
+		c.CTR = float64(c.Clicks) / float64(c.Impressions)
+	}
+}
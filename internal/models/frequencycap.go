@@ -0,0 +1,29 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Сколько раз баннер можно показать одному пользователю за окно в Window секунд.
+// Окна идут подряд от начала unix-времени, поэтому окно в 86400 секунд — сутки по UTC
+type FrequencyCap struct {
+	Count  int64 `json:"count"`
+	Window int64 `json:"window"`
+}
+
+// Нулевое ограничение в PATCH снимает ограничение баннера
+func (c FrequencyCap) IsZero() bool {
+	return c.Count == 0 && c.Window == 0
+}
+
+func (c FrequencyCap) Valid() bool {
+	return c.IsZero() || (c.Count > 0 && c.Window > 0)
+}
+
+// Ключ счётчика показов пользователю в текущем окне и время до конца окна
+func (c FrequencyCap) CounterKey(bannerID, userID int64, now time.Time) (string, time.Duration) {
+	start := now.Unix() / c.Window * c.Window
+	end := time.Unix(start+c.Window, 0)
+	return fmt.Sprintf("frequency_cap_%d_%d_%d", bannerID, userID, start), end.Sub(now)
+}
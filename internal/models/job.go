@@ -0,0 +1,18 @@
+package models
+
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+type Job struct {
+	ID        int64     `json:"id"`
+	Status    JobStatus `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt UnixTime  `json:"created_at"`
+	UpdatedAt UnixTime  `json:"updated_at"`
+}
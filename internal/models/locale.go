@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Содержимое баннера на одном из языков. Ключ — тег языка в нижнем регистре ("en", "pt-br")
+type LocalizedContent map[string]json.RawMessage
+
+// Выбор содержимого по списку языков в порядке предпочтения. Для тега с регионом после него
+// пробуется язык без региона ("pt-br", затем "pt"). Если подходящего перевода нет,
+// возвращается основное содержимое баннера с пустым языком
+func (b Banner) Localize(locales []string) (string, json.RawMessage) {
+	if len(b.LocalizedContent) == 0 {
+		return "", b.Content
+	}
+
+	for _, locale := range locales {
+		locale = strings.ToLower(locale)
+		for locale != "" {
+			if content, ok := b.LocalizedContent[locale]; ok {
+				return locale, content
+			}
+
+			cut := strings.LastIndexByte(locale, '-')
+			if cut < 0 {
+				break
+			}
+			locale = locale[:cut]
+		}
+	}
+
+	return "", b.Content
+}
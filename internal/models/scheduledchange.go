@@ -0,0 +1,21 @@
+package models
+
+type ScheduledChangeStatus string
+
+const (
+	ScheduledChangePending   ScheduledChangeStatus = "pending"
+	ScheduledChangeApplied   ScheduledChangeStatus = "applied"
+	ScheduledChangeFailed    ScheduledChangeStatus = "failed"
+	ScheduledChangeCancelled ScheduledChangeStatus = "cancelled"
+)
+
+// Изменение баннера, которое планировщик применит в ApplyAt
+type ScheduledChange struct {
+	ID        int64                 `json:"id"`
+	BannerID  int64                 `json:"banner_id"`
+	Change    BannerPatch           `json:"change"`
+	ApplyAt   UnixTime              `json:"apply_at"`
+	Status    ScheduledChangeStatus `json:"status"`
+	Error     string                `json:"error,omitempty"`
+	CreatedAt UnixTime              `json:"created_at"`
+}
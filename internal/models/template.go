@@ -0,0 +1,225 @@
+package models
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Источники значений для плейсхолдеров {{source.name}} в строках содержимого баннера
+const (
+	TemplateSourceClaims = "claims"
+	TemplateSourceQuery  = "query"
+	TemplateSourceServer = "server"
+)
+
+// Значения, которыми заполняются плейсхолдеры при выдаче баннера пользователю
+type TemplateData struct {
+	Claims map[string]any
+	Query  url.Values
+	Now    time.Time
+}
+
+// Плейсхолдер {{source.name|default}}. Значение по умолчанию подставляется,
+// если у источника нет такого значения
+type placeholder struct {
+	source       string
+	name         string
+	defaultValue string
+}
+
+// Проверка всех плейсхолдеров в строках содержимого. Содержимое без плейсхолдеров всегда корректно
+func ValidateTemplate(content json.RawMessage) error {
+	if !HasTemplate(content) {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(content, &value); err != nil {
+		return err
+	}
+
+	var err error
+	walkTemplateStrings(value, func(s string) string {
+		if err == nil {
+			_, err = renderString(s, nil)
+		}
+		return s
+	})
+	return err
+}
+
+// Подстановка значений в строки содержимого. Строки с некорректными плейсхолдерами,
+// сохранённые до появления шаблонов, отдаются как есть
+func RenderTemplate(content json.RawMessage, data TemplateData) (json.RawMessage, error) {
+	if !HasTemplate(content) {
+		return content, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	value = walkTemplateStrings(value, func(s string) string {
+		rendered, err := renderString(s, &data)
+		if err != nil {
+			return s
+		}
+		return rendered
+	})
+
+	// HTML-символы остаются экранированными, чтобы подставленный параметр запроса
+	// не превратился в разметку, если клиент вставит строку в страницу
+	return json.Marshal(value)
+}
+
+// Есть ли в содержимом плейсхолдеры. Такое содержимое зависит от пользователя и запроса
+func HasTemplate(content json.RawMessage) bool {
+	return bytes.Contains(content, []byte("{{"))
+}
+
+// Обход всех строковых значений, ключи объектов не меняются
+func walkTemplateStrings(value any, fn func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case []any:
+		for i := range v {
+			v[i] = walkTemplateStrings(v[i], fn)
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = walkTemplateStrings(v[key], fn)
+		}
+	}
+	return value
+}
+
+// Разбор строки с плейсхолдерами. Без data только проверяет плейсхолдеры
+func renderString(s string, data *TemplateData) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return "", fmt.Errorf("unclosed %q: %w", s[start:], errs.ErrInvalidPlaceholder)
+		}
+
+		p, err := parsePlaceholder(s[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+
+		out.WriteString(s[:start])
+		if data != nil {
+			value, ok := data.lookup(p)
+			if !ok {
+				value = p.defaultValue
+			}
+			out.WriteString(value)
+		}
+		s = s[start+end+2:]
+	}
+}
+
+func parsePlaceholder(expr string) (placeholder, error) {
+	var p placeholder
+	path, defaultValue, _ := strings.Cut(expr, "|")
+	p.defaultValue = strings.TrimSpace(defaultValue)
+
+	source, name, ok := strings.Cut(strings.TrimSpace(path), ".")
+	if !ok || !validPlaceholderName(name) {
+		return placeholder{}, fmt.Errorf("%q: %w", expr, errs.ErrInvalidPlaceholder)
+	}
+	p.source, p.name = source, name
+
+	switch source {
+	case TemplateSourceClaims, TemplateSourceQuery:
+	case TemplateSourceServer:
+		if _, err := serverValue(name, time.Time{}); err != nil {
+			return placeholder{}, fmt.Errorf("%q: %s: %w", expr, err, errs.ErrInvalidPlaceholder)
+		}
+	default:
+		return placeholder{}, fmt.Errorf("%q: unknown source %q: %w", expr, source, errs.ErrInvalidPlaceholder)
+	}
+	return p, nil
+}
+
+func validPlaceholderName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func (d TemplateData) lookup(p placeholder) (string, bool) {
+	switch p.source {
+	case TemplateSourceClaims:
+		value, ok := d.Claims[p.name]
+		if !ok || value == nil {
+			return "", false
+		}
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		default:
+			// Вложенные объекты в текст не подставляются
+			return "", false
+		}
+	case TemplateSourceQuery:
+		if !d.Query.Has(p.name) {
+			return "", false
+		}
+		return d.Query.Get(p.name), true
+	case TemplateSourceServer:
+		value, err := serverValue(p.name, d.Now)
+		return value, err == nil
+	}
+	return "", false
+}
+
+// Серверные значения: now — unix-время, date — дата по UTC,
+// countdown.<unix> — сколько секунд осталось до момента, но не меньше нуля
+func serverValue(name string, now time.Time) (string, error) {
+	switch name {
+	case "now":
+		return strconv.FormatInt(now.Unix(), 10), nil
+	case "date":
+		return now.UTC().Format(time.DateOnly), nil
+	}
+
+	if deadline, ok := strings.CutPrefix(name, "countdown."); ok {
+		until, err := strconv.ParseInt(deadline, 10, 64)
+		if err != nil {
+			return "", errors.New("countdown deadline must be unix time")
+		}
+		left := until - now.Unix()
+		if left < 0 {
+			left = 0
+		}
+		return strconv.FormatInt(left, 10), nil
+	}
+	return "", fmt.Errorf("unknown server value %q", name)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// Имя, под которым отдаётся основное содержимое баннера без вариантов
+const DefaultVariantName = "default"
+
+// Вариант содержимого баннера для A/B-теста. Доля показов варианта пропорциональна его весу
+type BannerVariant struct {
+	Name    string          `json:"name"`
+	Content json.RawMessage `json:"content"`
+	Weight  int             `json:"weight"`
+}
+
+// Выбор варианта для пользователя. Выбор зависит только от баннера, пользователя и набора
+// вариантов с весами, поэтому пользователь видит один и тот же вариант, пока их не поменяют.
+// Баннер без вариантов отдаёт основное содержимое под именем DefaultVariantName
+func (b Banner) PickVariant(userID int64) (string, json.RawMessage) {
+	var total uint64
+	for _, variant := range b.Variants {
+		total += uint64(variant.Weight)
+	}
+	if total == 0 {
+		return DefaultVariantName, b.Content
+	}
+
+	variants := make([]BannerVariant, len(b.Variants))
+	copy(variants, b.Variants)
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Name < variants[j].Name })
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%d", b.ID, userID)
+	point := h.Sum64() % total
+
+	for _, variant := range variants {
+		weight := uint64(variant.Weight)
+		if point < weight {
+			return variant.Name, variant.Content
+		}
+		point -= weight
+	}
+	return DefaultVariantName, b.Content
+}
@@ -3,7 +3,8 @@ package models
 import "encoding/json"
 
 type BannerVersion struct {
-	BannerID  int64           `json:"banner_id"`
-	Content   json.RawMessage `json:"content"`
-	UpdatedAt UnixTime        `json:"updated_at"`
+	BannerID         int64            `json:"banner_id"`
+	Content          json.RawMessage  `json:"content"`
+	LocalizedContent LocalizedContent `json:"localized_content,omitempty"`
+	UpdatedAt        UnixTime         `json:"updated_at"`
 }
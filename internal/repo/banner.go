@@ -13,6 +13,10 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// Варианты баннера одним JSON-массивом в порядке имён, NULL — вариантов нет
+const variantsColumn = "(SELECT json_agg(json_build_object('name', v.name, 'content', v.content, 'weight', v.weight) ORDER BY v.name) " +
+	"FROM banner_variants v WHERE v.banner_id = b.id) AS variants"
+
 type BannerRepository struct {
 	db *postgres.Postgres
 }
@@ -23,7 +27,9 @@ func NewBannerRepository(db *postgres.Postgres) *BannerRepository {
 
 func (r *BannerRepository) GetBanner(ctx context.Context, tagID, featureID int64, onlyActive bool) (models.Banner, error) {
 	const op = "BannerRepository.GetBanner"
-	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at").
+	// Все теги баннера нужны, чтобы положить его в кэш под каждой парой тег-фича
+	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at",
+		"(SELECT json_agg(tag_id) FROM banner_mappings WHERE banner_id = b.id) AS tag_ids", "bm.feature_id", "b.revision", "b.active_from", "b.active_until", variantsColumn, "b.frequency_cap", "b.localized_content").
 		From("banners b").
 		Join("banner_mappings bm ON b.id = bm.banner_id").
 		Where(squirrel.Eq{
@@ -32,7 +38,9 @@ func (r *BannerRepository) GetBanner(ctx context.Context, tagID, featureID int64
 		})
 
 	if onlyActive {
-		builder = builder.Where(squirrel.Eq{"bm.is_active": true})
+		builder = builder.Where(squirrel.Eq{"bm.is_active": true}).
+			Where("(b.active_from IS NULL OR b.active_from <= now())").
+			Where("(b.active_until IS NULL OR b.active_until > now())")
 	}
 
 	builder = builder.PlaceholderFormat(squirrel.Dollar).Limit(1)
@@ -43,9 +51,11 @@ func (r *BannerRepository) GetBanner(ctx context.Context, tagID, featureID int64
 	}
 
 	var banner models.Banner
+	var tagIDsStr string
+	var window windowScanner
 
 	err = r.db.QueryRow(ctx, query, args...).Scan(
-		&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt,
+		&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt, &tagIDsStr, &banner.FeatureID, &banner.Revision, &window.from, &window.until, &banner.Variants, &banner.FrequencyCap, &banner.LocalizedContent,
 	)
 	if err != nil {
 		if postgres.IfErrNoRows(err) {
@@ -54,17 +64,81 @@ func (r *BannerRepository) GetBanner(ctx context.Context, tagID, featureID int64
 		return models.Banner{}, errs.Wrap(op, "failed to execute SQL query", err)
 	}
 
+	banner.TagIds = make([]int64, 0)
+	err = json.Unmarshal([]byte(tagIDsStr), &banner.TagIds)
+	if err != nil {
+		return models.Banner{}, errs.Wrap(op, "failed to unmarshal tag IDs", err)
+	}
+	banner.ActiveWindow = window.window()
+
 	return banner, nil
 }
 
+// Баннеры для набора пар тег-фича одним запросом. Пары без баннера в результат не попадают,
+// баннер, найденный по нескольким своим парам, может встретиться несколько раз
+func (r *BannerRepository) GetBanners(ctx context.Context, keys []models.BannerKey) ([]models.Banner, error) {
+	const op = "BannerRepository.GetBanners"
+
+	tagIDs := make([]int64, 0, len(keys))
+	featureIDs := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		tagIDs = append(tagIDs, key.TagID)
+		featureIDs = append(featureIDs, key.FeatureID)
+	}
+
+	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at",
+		"(SELECT json_agg(tag_id) FROM banner_mappings WHERE banner_id = b.id) AS tag_ids", "bm.feature_id", "b.revision", "b.active_from", "b.active_until", variantsColumn, "b.frequency_cap", "b.localized_content").
+		From("banners b").
+		Join("banner_mappings bm ON b.id = bm.banner_id").
+		Where("(bm.tag_id, bm.feature_id) IN (SELECT * FROM unnest(?::bigint[], ?::bigint[]))", tagIDs, featureIDs).
+		PlaceholderFormat(squirrel.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+	defer rows.Close()
+
+	banners := make([]models.Banner, 0, len(keys))
+	for rows.Next() {
+		var banner models.Banner
+		var tagIDsStr string
+		var window windowScanner
+
+		err = rows.Scan(&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt, &tagIDsStr, &banner.FeatureID, &banner.Revision, &window.from, &window.until, &banner.Variants, &banner.FrequencyCap, &banner.LocalizedContent)
+		if err != nil {
+			return nil, errs.Wrap(op, "failed to scan row", err)
+		}
+
+		banner.TagIds = make([]int64, 0)
+		err = json.Unmarshal([]byte(tagIDsStr), &banner.TagIds)
+		if err != nil {
+			return nil, errs.Wrap(op, "failed to unmarshal tag IDs", err)
+		}
+		banner.ActiveWindow = window.window()
+
+		banners = append(banners, banner)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.Wrap(op, "failed to iterate over rows", err)
+	}
+
+	return banners, nil
+}
+
 func (r *BannerRepository) GetBannerByID(ctx context.Context, id int64) (models.Banner, error) {
 	const op = "BannerRepository.GetBannerByID"
 
-	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "json_agg(bm.tag_id) as tag_ids", "bm.feature_id").
+	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "json_agg(bm.tag_id) as tag_ids", "bm.feature_id", "b.revision", "b.active_from", "b.active_until", variantsColumn, "b.frequency_cap", "b.localized_content").
 		From("banners b").
 		Join("banner_mappings bm ON b.id = bm.banner_id").
 		Where(squirrel.Eq{"b.id": id}).
-		GroupBy("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "bm.feature_id").
+		GroupBy("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "bm.feature_id", "b.revision", "b.active_from", "b.active_until", "b.frequency_cap", "b.localized_content").
 		OrderBy("b.id").
 		Limit(1)
 
@@ -75,9 +149,10 @@ func (r *BannerRepository) GetBannerByID(ctx context.Context, id int64) (models.
 
 	var banner models.Banner
 	var tagIDsStr string
+	var window windowScanner
 
 	err = r.db.QueryRow(ctx, query, args...).Scan(
-		&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt, &tagIDsStr, &banner.FeatureID,
+		&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt, &tagIDsStr, &banner.FeatureID, &banner.Revision, &window.from, &window.until, &banner.Variants, &banner.FrequencyCap, &banner.LocalizedContent,
 	)
 	if err != nil {
 		if postgres.IfErrNoRows(err) {
@@ -91,6 +166,7 @@ func (r *BannerRepository) GetBannerByID(ctx context.Context, id int64) (models.
 	if err != nil {
 		return models.Banner{}, errs.Wrap(op, "failed to unmarshal tag IDs", err)
 	}
+	banner.ActiveWindow = window.window()
 
 	return banner, nil
 }
@@ -98,7 +174,7 @@ func (r *BannerRepository) GetBannerByID(ctx context.Context, id int64) (models.
 func (r *BannerRepository) ListBanners(ctx context.Context, featureID, tagID n.NullInt64, limit, offset n.NullUint64) ([]models.Banner, error) {
 	const op = "BannerRepository.ListBanners"
 
-	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "json_agg(bm.tag_id) as tag_ids", "bm.feature_id").
+	builder := squirrel.Select("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "json_agg(bm.tag_id) as tag_ids", "bm.feature_id", "b.revision", "b.active_from", "b.active_until", variantsColumn, "b.frequency_cap", "b.localized_content").
 		From("banners b").
 		Join("banner_mappings bm ON b.id = bm.banner_id")
 
@@ -118,7 +194,7 @@ func (r *BannerRepository) ListBanners(ctx context.Context, featureID, tagID n.N
 		builder = builder.Offset(offset.Uint64)
 	}
 
-	builder = builder.GroupBy("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "bm.feature_id").OrderBy("b.id")
+	builder = builder.GroupBy("b.id", "b.content", "bm.is_active", "b.created_at", "b.updated_at", "bm.feature_id", "b.revision", "b.active_from", "b.active_until", "b.frequency_cap", "b.localized_content").OrderBy("b.id")
 
 	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
@@ -136,7 +212,8 @@ func (r *BannerRepository) ListBanners(ctx context.Context, featureID, tagID n.N
 		var banner models.Banner
 
 		var tagIDsStr string
-		err = rows.Scan(&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt, &tagIDsStr, &banner.FeatureID)
+		var window windowScanner
+		err = rows.Scan(&banner.ID, &banner.Content, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt, &tagIDsStr, &banner.FeatureID, &banner.Revision, &window.from, &window.until, &banner.Variants, &banner.FrequencyCap, &banner.LocalizedContent)
 		if err != nil {
 			return nil, errs.Wrap(op, "failed to scan row", err)
 		}
@@ -146,6 +223,7 @@ func (r *BannerRepository) ListBanners(ctx context.Context, featureID, tagID n.N
 		if err != nil {
 			return nil, errs.Wrap(op, "failed to unmarshal tag IDs", err)
 		}
+		banner.ActiveWindow = window.window()
 		banners = append(banners, banner)
 	}
 
@@ -156,7 +234,7 @@ func (r *BannerRepository) ListBanners(ctx context.Context, featureID, tagID n.N
 	return banners, nil
 }
 
-func (r *BannerRepository) CreateBanner(ctx context.Context, tagIDs []int64, featureID int64, content json.RawMessage, isActive bool) (int64, error) {
+func (r *BannerRepository) CreateBanner(ctx context.Context, input models.BannerInput) (int64, error) {
 	const op = "BannerRepository.CreateBanner"
 
 	// Начало транзакции
@@ -167,14 +245,17 @@ func (r *BannerRepository) CreateBanner(ctx context.Context, tagIDs []int64, fea
 	defer tx.Rollback(ctx)
 
 	// Вставка баннера в таблицу banners
-	bannerID, err := r.insertBanner(ctx, tx, content)
+	bannerID, err := r.insertBanner(ctx, tx, input)
 	if err != nil {
+		if postgres.IfCheckViolation(err) {
+			return 0, errs.Wrap(op, "invalid active window", errs.ErrInvalidValue)
+		}
 		return 0, errs.Wrap(op, "failed to insert banner", err)
 	}
 
 	// Вставка связей в banner_mappings
-	for _, tagID := range tagIDs {
-		err = r.insertBannerMappings(ctx, tx, bannerID, featureID, tagID, isActive)
+	for _, tagID := range input.TagIDs {
+		err = r.insertBannerMappings(ctx, tx, bannerID, input.FeatureID, tagID, input.IsActive)
 		if err != nil {
 			if postgres.IfUniqueViolation(err) {
 				return 0, errs.Wrap(op, "unique constraint violated", errs.ErrUniqueViolation)
@@ -183,6 +264,16 @@ func (r *BannerRepository) CreateBanner(ctx context.Context, tagIDs []int64, fea
 		}
 	}
 
+	err = r.insertBannerVariants(ctx, tx, bannerID, input.Variants)
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.notifyBannerChange(ctx, tx, models.BannerChange{BannerID: bannerID, FeatureIDs: []int64{input.FeatureID}, TagIDs: input.TagIDs})
+	if err != nil {
+		return 0, err
+	}
+
 	// Коммит
 	err = tx.Commit(ctx)
 	if err != nil {
@@ -192,12 +283,12 @@ func (r *BannerRepository) CreateBanner(ctx context.Context, tagIDs []int64, fea
 	return bannerID, nil
 }
 
-func (r *BannerRepository) insertBanner(ctx context.Context, tx pgx.Tx, content json.RawMessage) (int64, error) {
+func (r *BannerRepository) insertBanner(ctx context.Context, tx pgx.Tx, input models.BannerInput) (int64, error) {
 	const op = "BannerRepository.insertBanner"
 
 	query, args, err := squirrel.Insert("banners").
-		Columns("content").
-		Values(content).
+		Columns("content", "localized_content", "active_from", "active_until", "frequency_cap").
+		Values(input.Content, localizedContentValue(input.LocalizedContent), windowBound(input.ActiveFrom), windowBound(input.ActiveUntil), frequencyCapValue(input.FrequencyCap)).
 		Suffix("RETURNING id").
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
@@ -236,51 +327,155 @@ func (r *BannerRepository) insertBannerMappings(ctx context.Context, tx pgx.Tx,
 	return nil
 }
 
-func (r *BannerRepository) UpdateBanner(ctx context.Context, id int64, tagIDs []int64, featureID n.NullInt64, content json.RawMessage, isActive n.NullBool) error {
+// Обновление баннера, если его ревизия всё ещё равна expectedRevision (без проверки, если она не задана).
+// Возвращает новую ревизию, при несовпадении — errs.ErrRevisionMismatch
+func (r *BannerRepository) UpdateBanner(ctx context.Context, id int64, expectedRevision n.NullInt64, patch models.BannerPatch) (int64, error) {
 	const op = "BannerRepository.UpdateBanner"
 
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return errs.Wrap(op, "failed to begin transaction", err)
+		return 0, errs.Wrap(op, "failed to begin transaction", err)
 	}
 	defer tx.Rollback(ctx)
 
-	if content != nil && string(content) != "null" {
+	// Ревизия поднимается первой: строка баннера блокируется до конца транзакции,
+	// и параллельное изменение с той же ожидаемой ревизией получит несовпадение
+	revision, err := r.bumpRevision(ctx, tx, id, expectedRevision)
+	if err != nil {
+		return 0, err
+	}
+
+	// Фича и теги до изменения, чтобы другие инстансы сбросили и старые ключи кэша
+	before, err := r.selectBannerChange(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if (patch.Content != nil && string(patch.Content) != "null") || len(patch.LocalizedContent) > 0 {
 		// Обновление записи в таблице banner
-		err = r.updateBanner(ctx, tx, id, content)
+		err = r.updateBanner(ctx, tx, id, patch.Content, patch.LocalizedContent)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		// Создание новой записи в таблице banners_histpry
-		err = r.createBannerHistory(ctx, tx, id, content)
+		err = r.createBannerHistory(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if patch.ActiveFrom.Valid || patch.ActiveUntil.Valid {
+		err = r.updateBannerWindow(ctx, tx, id, patch.ActiveWindow)
 		if err != nil {
-			return err
+			return 0, err
+		}
+	}
+
+	if patch.FrequencyCap != nil {
+		err = r.updateBannerFrequencyCap(ctx, tx, id, patch.FrequencyCap)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Переданный список вариантов заменяет прежний целиком, пустой список удаляет все варианты
+	if patch.Variants != nil {
+		err = r.replaceBannerVariants(ctx, tx, id, patch.Variants)
+		if err != nil {
+			return 0, err
 		}
 	}
 
 	// Замена старых связей новыми в bannerMappings
-	if len(tagIDs) > 0 || featureID.Valid || isActive.Valid {
-		err = r.updateBannerMappings(ctx, tx, id, tagIDs, featureID, isActive)
+	if len(patch.TagIDs) > 0 || patch.FeatureID.Valid || patch.IsActive.Valid {
+		err = r.updateBannerMappings(ctx, tx, id, patch.TagIDs, patch.FeatureID, patch.IsActive)
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
+	after, err := r.selectBannerChange(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.notifyBannerChange(ctx, tx, mergeBannerChange(before, after))
+	if err != nil {
+		return 0, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
-		return errs.Wrap(op, "failed to commit transaction", err)
+		return 0, errs.Wrap(op, "failed to commit transaction", err)
 	}
 
-	return nil
+	return revision, nil
+}
+
+func (r *BannerRepository) bumpRevision(ctx context.Context, tx pgx.Tx, id int64, expectedRevision n.NullInt64) (int64, error) {
+	const op = "BannerRepository.bumpRevision"
+
+	builder := squirrel.Update("banners").
+		Set("revision", squirrel.Expr("revision + 1")).
+		Where(squirrel.Eq{"id": id}).
+		Suffix("RETURNING revision")
+	if expectedRevision.Valid {
+		builder = builder.Where(squirrel.Eq{"revision": expectedRevision.Int64})
+	}
+
+	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	var revision int64
+	err = tx.QueryRow(ctx, query, args...).Scan(&revision)
+	if err == nil {
+		return revision, nil
+	}
+	if !postgres.IfErrNoRows(err) {
+		return 0, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	// Строки нет либо совсем, либо с ожидаемой ревизией
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM banners WHERE id = $1)", id).Scan(&exists)
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+	if !exists {
+		return 0, errs.Wrap(op, "banner not found", errs.ErrNotFound)
+	}
+	return 0, errs.Wrap(op, "banner was changed concurrently", errs.ErrRevisionMismatch)
 }
 
-func (r *BannerRepository) updateBanner(ctx context.Context, tx pgx.Tx, id int64, content json.RawMessage) error {
+// Переводы сливаются с сохранёнными: переданный язык заменяется, null удаляет перевод
+func (r *BannerRepository) updateBanner(ctx context.Context, tx pgx.Tx, id int64, content json.RawMessage, localizedContent models.LocalizedContent) error {
 	const op = "BannerRepository.updateBanner"
 
 	builder := squirrel.Update("banners").Where(squirrel.Eq{"id": id})
-	if content != nil || string(content) != "null" {
+	if content != nil && string(content) != "null" {
 		builder = builder.Set("content", content)
 	}
+	if len(localizedContent) > 0 {
+		removed := make([]string, 0)
+		updated := make(models.LocalizedContent)
+		for locale, localized := range localizedContent {
+			if localized == nil || string(localized) == "null" {
+				removed = append(removed, locale)
+				continue
+			}
+			updated[locale] = localized
+		}
+
+		data, err := json.Marshal(updated)
+		if err != nil {
+			return errs.Wrap(op, "failed to marshal localized content", err)
+		}
+		builder = builder.Set("localized_content", squirrel.Expr(
+			"NULLIF((COALESCE(localized_content, '{}'::jsonb) - ?::text[]) || ?::jsonb, '{}'::jsonb)", removed, data,
+		))
+	}
 
 	builder = builder.Set("updated_at", time.Now())
 	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
@@ -301,12 +496,94 @@ func (r *BannerRepository) updateBanner(ctx context.Context, tx pgx.Tx, id int64
 	return nil
 }
 
-func (r *BannerRepository) createBannerHistory(ctx context.Context, tx pgx.Tx, bannerID int64, content json.RawMessage) error {
+// Меняются только переданные границы окна, нулевая граница снимается
+func (r *BannerRepository) updateBannerWindow(ctx context.Context, tx pgx.Tx, id int64, window models.ActiveWindow) error {
+	const op = "BannerRepository.updateBannerWindow"
+
+	builder := squirrel.Update("banners").Where(squirrel.Eq{"id": id})
+	if window.ActiveFrom.Valid {
+		builder = builder.Set("active_from", windowBound(window.ActiveFrom))
+	}
+	if window.ActiveUntil.Valid {
+		builder = builder.Set("active_until", windowBound(window.ActiveUntil))
+	}
+
+	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+	if err != nil {
+		if postgres.IfCheckViolation(err) {
+			return errs.Wrap(op, "invalid active window", errs.ErrInvalidValue)
+		}
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return nil
+}
+
+func (r *BannerRepository) updateBannerFrequencyCap(ctx context.Context, tx pgx.Tx, id int64, frequencyCap *models.FrequencyCap) error {
+	const op = "BannerRepository.updateBannerFrequencyCap"
+
+	_, err := tx.Exec(ctx, "UPDATE banners SET frequency_cap=$1 WHERE id=$2", frequencyCapValue(frequencyCap), id)
+	if err != nil {
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return nil
+}
+
+func (r *BannerRepository) replaceBannerVariants(ctx context.Context, tx pgx.Tx, id int64, variants []models.BannerVariant) error {
+	const op = "BannerRepository.replaceBannerVariants"
+
+	_, err := tx.Exec(ctx, "DELETE FROM banner_variants WHERE banner_id=$1", id)
+	if err != nil {
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return r.insertBannerVariants(ctx, tx, id, variants)
+}
+
+func (r *BannerRepository) insertBannerVariants(ctx context.Context, tx pgx.Tx, id int64, variants []models.BannerVariant) error {
+	const op = "BannerRepository.insertBannerVariants"
+
+	if len(variants) == 0 {
+		return nil
+	}
+
+	builder := squirrel.Insert("banner_variants").Columns("banner_id", "name", "content", "weight")
+	for _, variant := range variants {
+		builder = builder.Values(id, variant.Name, variant.Content, variant.Weight)
+	}
+
+	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+	if err != nil {
+		if postgres.IfUniqueViolation(err) || postgres.IfCheckViolation(err) {
+			return errs.Wrap(op, "duplicate variant name or negative weight", errs.ErrInvalidVariants)
+		}
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return nil
+}
+
+// В историю попадает текущее содержимое баннера вместе со всеми переводами
+func (r *BannerRepository) createBannerHistory(ctx context.Context, tx pgx.Tx, bannerID int64) error {
 	const op = "BannerRepository.createBannerHistory"
 
 	query, args, err := squirrel.Insert("banners_history").
-		Columns("banner_id", "content", "updated_at").
-		Values(bannerID, content, time.Now()).
+		Columns("banner_id", "content", "localized_content", "updated_at").
+		Select(squirrel.Select("id", "content", "localized_content").
+			Column("?::timestamptz", time.Now()).
+			From("banners").
+			Where(squirrel.Eq{"id": bannerID})).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
@@ -385,6 +662,11 @@ func (r *BannerRepository) DeleteBanner(ctx context.Context, id int64) error {
 	}
 	defer tx.Rollback(ctx)
 
+	change, err := r.selectBannerChange(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
 	// Удаление связей из banner_mappings
 	_, err = tx.Exec(ctx, "DELETE FROM banner_mappings WHERE banner_id=$1", id)
 	if err != nil {
@@ -403,6 +685,11 @@ func (r *BannerRepository) DeleteBanner(ctx context.Context, id int64) error {
 		return errs.Wrap(op, "banner not found", errs.ErrNotFound)
 	}
 
+	err = r.notifyBannerChange(ctx, tx, change)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return errs.Wrap(op, "failed to commit transaction", err)
@@ -411,11 +698,129 @@ func (r *BannerRepository) DeleteBanner(ctx context.Context, id int64) error {
 	return nil
 }
 
+// Удаление пачки баннеров по фиче и/или тегу, возвращает удалённые баннеры с их фичей и тегами
+func (r *BannerRepository) DeleteBanners(ctx context.Context, featureID, tagID n.NullInt64, limit uint64) ([]models.Banner, error) {
+	const op = "BannerRepository.DeleteBanners"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to begin transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Выбор очередной пачки баннеров, подходящих под фильтр
+	bannerIDs, err := r.selectBannerIDs(ctx, tx, featureID, tagID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(bannerIDs) == 0 {
+		return nil, nil
+	}
+
+	// Удаление связей из banner_mappings, связи нужны вызывающему для инвалидации кэша
+	rows, err := tx.Query(ctx, "DELETE FROM banner_mappings WHERE banner_id = ANY($1) RETURNING banner_id, feature_id, tag_id", bannerIDs)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	bannersByID := make(map[int64]*models.Banner, len(bannerIDs))
+	for rows.Next() {
+		var bannerID, mappingFeatureID, mappingTagID int64
+		if err := rows.Scan(&bannerID, &mappingFeatureID, &mappingTagID); err != nil {
+			rows.Close()
+			return nil, errs.Wrap(op, "failed to scan row", err)
+		}
+
+		banner, ok := bannersByID[bannerID]
+		if !ok {
+			banner = &models.Banner{ID: bannerID, FeatureID: mappingFeatureID}
+			bannersByID[bannerID] = banner
+		}
+		banner.TagIds = append(banner.TagIds, mappingTagID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errs.Wrap(op, "failed to iterate over rows", err)
+	}
+
+	// Удаление баннеров из banners
+	_, err = tx.Exec(ctx, "DELETE FROM banners WHERE id = ANY($1)", bannerIDs)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	banners := make([]models.Banner, 0, len(bannerIDs))
+	for _, bannerID := range bannerIDs {
+		if banner, ok := bannersByID[bannerID]; ok {
+			banners = append(banners, *banner)
+		}
+	}
+
+	// По уведомлению на баннер, чтобы не упереться в ограничение размера payload
+	for _, banner := range banners {
+		change := models.BannerChange{BannerID: banner.ID, FeatureIDs: []int64{banner.FeatureID}, TagIDs: banner.TagIds}
+		err = r.notifyBannerChange(ctx, tx, change)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to commit transaction", err)
+	}
+
+	return banners, nil
+}
+
+func (r *BannerRepository) selectBannerIDs(ctx context.Context, tx pgx.Tx, featureID, tagID n.NullInt64, limit uint64) ([]int64, error) {
+	const op = "BannerRepository.selectBannerIDs"
+
+	builder := squirrel.Select("DISTINCT banner_id").
+		From("banner_mappings")
+
+	if featureID.Valid {
+		builder = builder.Where(squirrel.Eq{"feature_id": featureID.Int64})
+	}
+
+	if tagID.Valid {
+		builder = builder.Where(squirrel.Eq{"tag_id": tagID.Int64})
+	}
+
+	builder = builder.OrderBy("banner_id").Limit(limit)
+
+	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+	defer rows.Close()
+
+	bannerIDs := make([]int64, 0, limit)
+	for rows.Next() {
+		var bannerID int64
+		if err := rows.Scan(&bannerID); err != nil {
+			return nil, errs.Wrap(op, "failed to scan row", err)
+		}
+		bannerIDs = append(bannerIDs, bannerID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errs.Wrap(op, "failed to iterate over rows", err)
+	}
+
+	return bannerIDs, nil
+}
+
 func (r *BannerRepository) ListBannerVersions(ctx context.Context, bannerID int64) ([]models.BannerVersion, error) {
 	const op = "BannerRepository.ListBannerVersions"
 	var versions []models.BannerVersion
 
-	builder := squirrel.Select("banner_id", "content", "updated_at").
+	builder := squirrel.Select("banner_id", "content", "localized_content", "updated_at").
 		From("banners_history").
 		Where(squirrel.Eq{
 			"banner_id": bannerID,
@@ -435,7 +840,7 @@ func (r *BannerRepository) ListBannerVersions(ctx context.Context, bannerID int6
 
 	for rows.Next() {
 		var version models.BannerVersion
-		if err := rows.Scan(&version.BannerID, &version.Content, &version.UpdatedAt); err != nil {
+		if err := rows.Scan(&version.BannerID, &version.Content, &version.LocalizedContent, &version.UpdatedAt); err != nil {
 			return nil, errs.Wrap(op, "failed to scan row", err)
 		}
 		versions = append(versions, version)
@@ -467,6 +872,16 @@ func (r *BannerRepository) RestoreVersion(ctx context.Context, bannerID int64, u
 		return err
 	}
 
+	change, err := r.selectBannerChange(ctx, tx, bannerID)
+	if err != nil {
+		return err
+	}
+
+	err = r.notifyBannerChange(ctx, tx, change)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return errs.Wrap(op, "failed to commit transaction", err)
@@ -484,7 +899,7 @@ func (r *BannerRepository) deleteBannerVersion(ctx context.Context, tx pgx.Tx, b
 			"banner_id":  bannerID,
 			"updated_at": time.Unix(int64(updatedAt), 0),
 		}).
-		Suffix("RETURNING content")
+		Suffix("RETURNING content, localized_content")
 
 	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
 	if err != nil {
@@ -492,7 +907,7 @@ func (r *BannerRepository) deleteBannerVersion(ctx context.Context, tx pgx.Tx, b
 	}
 
 	row := tx.QueryRow(ctx, query, args...)
-	err = row.Scan(&deletedVersion.Content)
+	err = row.Scan(&deletedVersion.Content, &deletedVersion.LocalizedContent)
 	if err != nil {
 		if postgres.IfErrNoRows(err) {
 			return models.BannerVersion{}, errs.Wrap(op, "no banner versions", errs.ErrNotFound)
@@ -512,7 +927,9 @@ func (r *BannerRepository) restoreBannerVersion(ctx context.Context, tx pgx.Tx,
 
 	builder := squirrel.Update("banners").
 		Set("content", bannerVersion.Content).
+		Set("localized_content", localizedContentValue(bannerVersion.LocalizedContent)).
 		Set("updated_at", time.Now()).
+		Set("revision", squirrel.Expr("revision + 1")).
 		Where(squirrel.Eq{"id": bannerVersion.BannerID})
 
 	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
@@ -527,3 +944,45 @@ func (r *BannerRepository) restoreBannerVersion(ctx context.Context, tx pgx.Tx,
 
 	return nil
 }
+
+// Приём границ окна показа из nullable timestamptz
+type windowScanner struct {
+	from  *time.Time
+	until *time.Time
+}
+
+func (w windowScanner) window() models.ActiveWindow {
+	var window models.ActiveWindow
+	if w.from != nil {
+		window.ActiveFrom = n.NullInt64From(w.from.Unix())
+	}
+	if w.until != nil {
+		window.ActiveUntil = n.NullInt64From(w.until.Unix())
+	}
+	return window
+}
+
+// Граница окна для записи в базу: отсутствующая или нулевая граница записывается как NULL
+func windowBound(bound n.NullInt64) *time.Time {
+	if !bound.Valid || bound.Int64 == 0 {
+		return nil
+	}
+	t := time.Unix(bound.Int64, 0)
+	return &t
+}
+
+// Нулевое ограничение частоты показов хранится как NULL
+func frequencyCapValue(frequencyCap *models.FrequencyCap) *models.FrequencyCap {
+	if frequencyCap == nil || frequencyCap.IsZero() {
+		return nil
+	}
+	return frequencyCap
+}
+
+// Баннер без переводов хранит NULL
+func localizedContentValue(localizedContent models.LocalizedContent) models.LocalizedContent {
+	if len(localizedContent) == 0 {
+		return nil
+	}
+	return localizedContent
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"backend-trainee-assignment-2024/internal/storage/postgres"
+	"context"
+	"encoding/json"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type DeadLetterRepository struct {
+	db *postgres.Postgres
+}
+
+func NewDeadLetterRepository(db *postgres.Postgres) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
+
+func (r *DeadLetterRepository) AddDeadLetter(ctx context.Context, jobID int64, task json.RawMessage, errMsg string, attempts int) (int64, error) {
+	const op = "DeadLetterRepository.AddDeadLetter"
+
+	query, args, err := squirrel.Insert("dead_letters").
+		Columns("job_id", "task", "error", "attempts").
+		Values(jobID, task, errMsg, attempts).
+		Suffix("RETURNING id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	var id int64
+	err = r.db.QueryRow(ctx, query, args...).Scan(&id)
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return id, nil
+}
+
+// Удаление записи с возвратом её содержимого. Из двух одновременных вызовов запись получит только один
+func (r *DeadLetterRepository) TakeDeadLetter(ctx context.Context, id int64) (models.DeadLetter, error) {
+	const op = "DeadLetterRepository.TakeDeadLetter"
+
+	query, args, err := squirrel.Delete("dead_letters").
+		Where(squirrel.Eq{"id": id}).
+		Suffix("RETURNING id, job_id, task, error, attempts, created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return models.DeadLetter{}, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	var deadLetter models.DeadLetter
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&deadLetter.ID, &deadLetter.JobID, &deadLetter.Task, &deadLetter.Error, &deadLetter.Attempts, &deadLetter.CreatedAt,
+	)
+	if err != nil {
+		if postgres.IfErrNoRows(err) {
+			return models.DeadLetter{}, errs.Wrap(op, "dead letter not found", errs.ErrNotFound)
+		}
+		return models.DeadLetter{}, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return deadLetter, nil
+}
+
+func (r *DeadLetterRepository) ListDeadLetters(ctx context.Context, limit, offset n.NullUint64) ([]models.DeadLetter, error) {
+	const op = "DeadLetterRepository.ListDeadLetters"
+
+	builder := squirrel.Select("id", "job_id", "task", "error", "attempts", "created_at").
+		From("dead_letters").
+		OrderBy("id")
+
+	if limit.Valid {
+		builder = builder.Limit(limit.Uint64)
+	}
+
+	if offset.Valid {
+		builder = builder.Offset(offset.Uint64)
+	}
+
+	query, args, err := builder.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []models.DeadLetter
+	for rows.Next() {
+		var deadLetter models.DeadLetter
+		err = rows.Scan(&deadLetter.ID, &deadLetter.JobID, &deadLetter.Task, &deadLetter.Error, &deadLetter.Attempts, &deadLetter.CreatedAt)
+		if err != nil {
+			return nil, errs.Wrap(op, "failed to scan row", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, errs.Wrap(op, "failed to iterate over rows", err)
+	}
+
+	return deadLetters, nil
+}
+
+func (r *DeadLetterRepository) DeleteDeadLetter(ctx context.Context, id int64) error {
+	const op = "DeadLetterRepository.DeleteDeadLetter"
+
+	res, err := r.db.Exec(ctx, "DELETE FROM dead_letters WHERE id=$1", id)
+	if err != nil {
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.Wrap(op, "dead letter not found", errs.ErrNotFound)
+	}
+
+	return nil
+}
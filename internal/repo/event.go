@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/storage/postgres"
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+type EventRepository struct {
+	db *postgres.Postgres
+}
+
+func NewEventRepository(db *postgres.Postgres) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// Запись пачки событий одним COPY
+func (r *EventRepository) InsertEvents(ctx context.Context, events []models.Event) error {
+	const op = "EventRepository.InsertEvents"
+
+	_, err := r.db.CopyFrom(ctx,
+		pgx.Identifier{"banner_events"},
+		[]string{"banner_id", "variant", "tag_id", "user_id", "type", "created_at"},
+		pgx.CopyFromSlice(len(events), func(i int) ([]any, error) {
+			event := events[i]
+			return []any{event.BannerID, event.Variant, event.TagID, event.UserID, string(event.Type), event.CreatedAt}, nil
+		}),
+	)
+	if err != nil {
+		return errs.Wrap(op, "failed to copy events", err)
+	}
+
+	return nil
+}
+
+// Показы и клики баннера по суткам (UTC), начиная с from, с разбивкой по вариантам
+func (r *EventRepository) GetBannerStats(ctx context.Context, bannerID int64, from time.Time) ([]models.DailyStats, error) {
+	const op = "EventRepository.GetBannerStats"
+
+	query, args, err := squirrel.Select(
+		"to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day",
+		"variant",
+		"count(*) FILTER (WHERE type = 'impression')",
+		"count(*) FILTER (WHERE type = 'click')",
+	).
+		From("banner_events").
+		Where(squirrel.Eq{"banner_id": bannerID}).
+		Where(squirrel.GtOrEq{"created_at": from}).
+		GroupBy("day", "variant").
+		OrderBy("day", "variant").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.DailyStats, 0)
+	for rows.Next() {
+		var day string
+		var variant models.VariantStats
+		err := rows.Scan(&day, &variant.Variant, &variant.Impressions, &variant.Clicks)
+		if err != nil {
+			return nil, errs.Wrap(op, "failed to scan row", err)
+		}
+		variant.UpdateCTR()
+
+		if len(stats) == 0 || stats[len(stats)-1].Day != day {
+			stats = append(stats, models.DailyStats{Day: day, Variants: make([]models.VariantStats, 0)})
+		}
+		daily := &stats[len(stats)-1]
+		daily.Impressions += variant.Impressions
+		daily.Clicks += variant.Clicks
+		daily.UpdateCTR()
+		daily.Variants = append(daily.Variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.Wrap(op, "failed to iterate over rows", err)
+	}
+
+	return stats, nil
+}
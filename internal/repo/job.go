@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/storage/postgres"
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type JobRepository struct {
+	db *postgres.Postgres
+}
+
+func NewJobRepository(db *postgres.Postgres) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) CreateJob(ctx context.Context) (int64, error) {
+	const op = "JobRepository.CreateJob"
+
+	query, args, err := squirrel.Insert("jobs").
+		Columns("status").
+		Values(models.JobQueued).
+		Suffix("RETURNING id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	var jobID int64
+	err = r.db.QueryRow(ctx, query, args...).Scan(&jobID)
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return jobID, nil
+}
+
+func (r *JobRepository) UpdateJobStatus(ctx context.Context, id int64, status models.JobStatus, errMsg string) error {
+	const op = "JobRepository.UpdateJobStatus"
+
+	query, args, err := squirrel.Update("jobs").
+		Set("status", status).
+		Set("error", errMsg).
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	res, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.Wrap(op, "job not found", errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *JobRepository) GetJob(ctx context.Context, id int64) (models.Job, error) {
+	const op = "JobRepository.GetJob"
+
+	query, args, err := squirrel.Select("id", "status", "error", "created_at", "updated_at").
+		From("jobs").
+		Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return models.Job{}, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	var job models.Job
+	err = r.db.QueryRow(ctx, query, args...).Scan(&job.ID, &job.Status, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		if postgres.IfErrNoRows(err) {
+			return models.Job{}, errs.Wrap(op, "job not found", errs.ErrNotFound)
+		}
+		return models.Job{}, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return job, nil
+}
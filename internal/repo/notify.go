@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/storage/postgres"
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Канал LISTEN/NOTIFY, в который пишутся изменения баннеров
+const bannerChangesChannel = "banner_changes"
+
+// Подписка на изменения баннеров, сделанные любым инстансом сервиса.
+// Блокируется до отмены ctx или разрыва соединения
+func (r *BannerRepository) ListenBannerChanges(ctx context.Context, handle func(models.BannerChange)) error {
+	const op = "BannerRepository.ListenBannerChanges"
+
+	err := r.db.Listen(ctx, bannerChangesChannel, func(payload string) {
+		var change models.BannerChange
+		// Уведомления, записанные в канал не репозиторием, пропускаются
+		if err := json.Unmarshal([]byte(payload), &change); err != nil {
+			return
+		}
+		handle(change)
+	})
+	if err != nil {
+		return errs.Wrap(op, "failed to listen", err)
+	}
+
+	return nil
+}
+
+// Текущие фича и теги баннера внутри транзакции
+func (r *BannerRepository) selectBannerChange(ctx context.Context, tx pgx.Tx, bannerID int64) (models.BannerChange, error) {
+	const op = "BannerRepository.selectBannerChange"
+
+	rows, err := tx.Query(ctx, "SELECT feature_id, tag_id FROM banner_mappings WHERE banner_id=$1", bannerID)
+	if err != nil {
+		return models.BannerChange{}, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+	defer rows.Close()
+
+	change := models.BannerChange{BannerID: bannerID}
+	for rows.Next() {
+		var featureID, tagID int64
+		if err := rows.Scan(&featureID, &tagID); err != nil {
+			return models.BannerChange{}, errs.Wrap(op, "failed to scan row", err)
+		}
+		change = mergeBannerChange(change, models.BannerChange{FeatureIDs: []int64{featureID}, TagIDs: []int64{tagID}})
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.BannerChange{}, errs.Wrap(op, "failed to iterate over rows", err)
+	}
+
+	return change, nil
+}
+
+// Уведомление остальных инстансов об изменении баннера. Уходит только при коммите транзакции
+func (r *BannerRepository) notifyBannerChange(ctx context.Context, tx pgx.Tx, change models.BannerChange) error {
+	const op = "BannerRepository.notifyBannerChange"
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return errs.Wrap(op, "failed to encode notification", err)
+	}
+
+	err = postgres.Notify(ctx, tx, bannerChangesChannel, string(payload))
+	if err != nil {
+		return errs.Wrap(op, "failed to notify", err)
+	}
+
+	return nil
+}
+
+// Объединение фич и тегов двух изменений одного баннера без повторов
+func mergeBannerChange(change, other models.BannerChange) models.BannerChange {
+	for _, featureID := range other.FeatureIDs {
+		if !slices.Contains(change.FeatureIDs, featureID) {
+			change.FeatureIDs = append(change.FeatureIDs, featureID)
+		}
+	}
+	for _, tagID := range other.TagIDs {
+		if !slices.Contains(change.TagIDs, tagID) {
+			change.TagIDs = append(change.TagIDs, tagID)
+		}
+	}
+	return change
+}
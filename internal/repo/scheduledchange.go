@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/storage/postgres"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+type ScheduledChangeRepository struct {
+	db *postgres.Postgres
+}
+
+func NewScheduledChangeRepository(db *postgres.Postgres) *ScheduledChangeRepository {
+	return &ScheduledChangeRepository{db: db}
+}
+
+func (r *ScheduledChangeRepository) CreateScheduledChange(ctx context.Context, bannerID int64, applyAt models.UnixTime, change models.BannerPatch) (int64, error) {
+	const op = "ScheduledChangeRepository.CreateScheduledChange"
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to marshal change", err)
+	}
+
+	query, args, err := squirrel.Insert("scheduled_changes").
+		Columns("banner_id", "change", "apply_at").
+		Values(bannerID, data, time.Unix(int64(applyAt), 0)).
+		Suffix("RETURNING id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	var id int64
+	err = r.db.QueryRow(ctx, query, args...).Scan(&id)
+	if err != nil {
+		if postgres.IfForeignKeyViolation(err) {
+			return 0, errs.Wrap(op, "banner not found", errs.ErrNotFound)
+		}
+		return 0, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	return id, nil
+}
+
+// Ожидающие изменения баннера в порядке применения
+func (r *ScheduledChangeRepository) ListScheduledChanges(ctx context.Context, bannerID int64) ([]models.ScheduledChange, error) {
+	const op = "ScheduledChangeRepository.ListScheduledChanges"
+
+	query, args, err := squirrel.Select("id", "banner_id", "change", "apply_at", "status", "error", "created_at").
+		From("scheduled_changes").
+		Where(squirrel.Eq{"banner_id": bannerID, "status": models.ScheduledChangePending}).
+		OrderBy("apply_at", "id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	changes, err := scanScheduledChanges(rows)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to scan rows", err)
+	}
+
+	return changes, nil
+}
+
+// Отменить можно только ещё не применённое изменение
+func (r *ScheduledChangeRepository) CancelScheduledChange(ctx context.Context, bannerID, id int64) error {
+	const op = "ScheduledChangeRepository.CancelScheduledChange"
+
+	res, err := r.db.Exec(ctx,
+		"UPDATE scheduled_changes SET status=$1, updated_at=now() WHERE id=$2 AND banner_id=$3 AND status=$4",
+		models.ScheduledChangeCancelled, id, bannerID, models.ScheduledChangePending,
+	)
+	if err != nil {
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.Wrap(op, "scheduled change not found", errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// Захват изменений, срок которых наступил. Захваченное изменение не видно другим инстансам
+// на lockTimeout; если его не завершили за это время (например, сервис упал), оно будет захвачено снова
+func (r *ScheduledChangeRepository) ClaimDueScheduledChanges(ctx context.Context, limit uint64, lockTimeout time.Duration) ([]models.ScheduledChange, error) {
+	const op = "ScheduledChangeRepository.ClaimDueScheduledChanges"
+
+	rows, err := r.db.Query(ctx, `
+		UPDATE scheduled_changes SET locked_until = now() + make_interval(secs => $1)
+		WHERE id IN (
+			SELECT id FROM scheduled_changes
+			WHERE status = $2 AND apply_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY apply_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT $3
+		)
+		RETURNING id, banner_id, change, apply_at, status, error, created_at`,
+		lockTimeout.Seconds(), models.ScheduledChangePending, limit,
+	)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	changes, err := scanScheduledChanges(rows)
+	if err != nil {
+		return nil, errs.Wrap(op, "failed to scan rows", err)
+	}
+
+	return changes, nil
+}
+
+// Фиксация результата применения захваченного изменения
+func (r *ScheduledChangeRepository) FinishScheduledChange(ctx context.Context, id int64, status models.ScheduledChangeStatus, errMsg string) error {
+	const op = "ScheduledChangeRepository.FinishScheduledChange"
+
+	query, args, err := squirrel.Update("scheduled_changes").
+		Set("status", status).
+		Set("error", errMsg).
+		Set("locked_until", nil).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return errs.Wrap(op, "failed to build SQL query", err)
+	}
+
+	res, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return errs.Wrap(op, "failed to execute SQL query", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.Wrap(op, "scheduled change not found", errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+func scanScheduledChanges(rows pgx.Rows) ([]models.ScheduledChange, error) {
+	defer rows.Close()
+
+	changes := make([]models.ScheduledChange, 0)
+	for rows.Next() {
+		var change models.ScheduledChange
+		var data []byte
+		err := rows.Scan(&change.ID, &change.BannerID, &data, &change.ApplyAt, &change.Status, &change.Error, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(data, &change.Change)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
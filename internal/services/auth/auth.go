@@ -40,36 +40,47 @@ func (s *AuthService) GenerateToken(userID int, userType string) (string, error)
 
 // Функция для валидации и проверки типа пользователя
 func (s *AuthService) ValidateToken(tokenString string) (string, error) {
+	claims, err := s.Claims(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims["user_type"].(string), nil
+}
+
+// Все утверждения токена после тех же проверок, что и в ValidateToken.
+// Ими заполняются плейсхолдеры claims.* в содержимом баннера
+func (s *AuthService) Claims(tokenString string) (map[string]any, error) {
 	if tokenString == "" {
-		return "", errs.ErrRequiredToken
+		return nil, errs.ErrRequiredToken
 	}
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.secretKey, nil
-	})
+	token, err := jwt.Parse(tokenString, s.keyFunc)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		expirationTime, ok := claims["exp"].(float64)
 		if !ok {
-			return "", errs.ErrInvalidExpirationTime
+			return nil, errs.ErrInvalidExpirationTime
 		}
 
 		if time.Unix(int64(expirationTime), 0).Before(time.Now()) {
-			return "", errs.ErrTokenExpired
+			return nil, errs.ErrTokenExpired
 		}
 
-		userType, ok := claims["user_type"].(string)
-		if !ok {
-			return "", errs.ErrInvalidUserType
+		if _, ok := claims["user_type"].(string); !ok {
+			return nil, errs.ErrInvalidUserType
 		}
 
-		return userType, nil
+		return claims, nil
+	}
+	return nil, errs.ErrInvalidToken
+}
+
+func (s *AuthService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	}
-	return "", errs.ErrInvalidToken
+	return s.secretKey, nil
 }
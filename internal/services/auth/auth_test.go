@@ -58,3 +58,26 @@ func TestAuthService_ValidateToken(t *testing.T) {
 	_, err = authService.ValidateToken("invalid_token")
 	assert.ErrorIs(t, err, errs.ErrInvalidToken)
 }
+
+func TestAuthService_Claims(t *testing.T) {
+	authService := NewAuthService("secret_key")
+
+	token, err := authService.GenerateToken(7, "user")
+	assert.NoError(t, err)
+
+	claims, err := authService.Claims(token)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(7), claims["user_id"])
+	assert.Equal(t, "user", claims["user_type"])
+
+	_, err = authService.Claims("")
+	assert.ErrorIs(t, err, errs.ErrRequiredToken)
+	_, err = NewAuthService("other_key").Claims(token)
+	assert.Error(t, err)
+
+	// Токен без срока действия не принимается, как и в ValidateToken
+	noExpiration, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 7, "user_type": "user"}).SignedString([]byte("secret_key"))
+	assert.NoError(t, err)
+	_, err = authService.Claims(noExpiration)
+	assert.ErrorIs(t, err, errs.ErrInvalidExpirationTime)
+}
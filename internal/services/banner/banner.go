@@ -1,66 +1,208 @@
 package banner
 
 import (
+	"backend-trainee-assignment-2024/internal/breaker"
+	"backend-trainee-assignment-2024/internal/broker"
 	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
 	"backend-trainee-assignment-2024/internal/logger"
 	"backend-trainee-assignment-2024/internal/models"
 	n "backend-trainee-assignment-2024/internal/nullable"
+	"backend-trainee-assignment-2024/internal/storage/memory"
 
 	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 type BannerRepository interface {
 	GetBanner(ctx context.Context, tagID, featureID int64, onlyActive bool) (models.Banner, error)
+	GetBanners(ctx context.Context, keys []models.BannerKey) ([]models.Banner, error)
 	GetBannerByID(ctx context.Context, id int64) (models.Banner, error)
 	ListBanners(ctx context.Context, featureID, tagID n.NullInt64, limit, offset n.NullUint64) ([]models.Banner, error)
-	CreateBanner(ctx context.Context, tagIDs []int64, featureID int64, content json.RawMessage, isActive bool) (int64, error)
-	UpdateBanner(ctx context.Context, bannerID int64, tagIDs []int64, featureID n.NullInt64, content json.RawMessage, isActive n.NullBool) error
+	CreateBanner(ctx context.Context, input models.BannerInput) (int64, error)
+	UpdateBanner(ctx context.Context, bannerID int64, expectedRevision n.NullInt64, patch models.BannerPatch) (int64, error)
 	DeleteBanner(ctx context.Context, bannerID int64) error
+	DeleteBanners(ctx context.Context, featureID, tagID n.NullInt64, limit uint64) ([]models.Banner, error)
 	ListBannerVersions(ctx context.Context, bannerID int64) ([]models.BannerVersion, error)
 	RestoreVersion(ctx context.Context, bannerID int64, updatedAt models.UnixTime) error
 }
 
+type JobRepository interface {
+	CreateJob(ctx context.Context) (int64, error)
+	UpdateJobStatus(ctx context.Context, id int64, status models.JobStatus, errMsg string) error
+	GetJob(ctx context.Context, id int64) (models.Job, error)
+}
+
+type DeadLetterRepository interface {
+	AddDeadLetter(ctx context.Context, jobID int64, task json.RawMessage, errMsg string, attempts int) (int64, error)
+	TakeDeadLetter(ctx context.Context, id int64) (models.DeadLetter, error)
+	ListDeadLetters(ctx context.Context, limit, offset n.NullUint64) ([]models.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id int64) error
+}
+
+// Источник изменений баннеров, сделанных любым инстансом сервиса
+type BannerChangeSource interface {
+	ListenBannerChanges(ctx context.Context, handle func(models.BannerChange)) error
+}
+
 type Cache interface {
 	Push(key, value string, ttl time.Duration) (err error)
 	Get(key string) (value string, err error)
+	// Отсутствующих ключей в результате нет
+	GetMany(keys []string) (values map[string]string, err error)
 	Remove(key string) (err error)
+	// Атомарное увеличение счётчика. Новый счётчик живёт ttl
+	Incr(key string, ttl time.Duration) (value int64, err error)
 }
 
 type Queue interface {
 	Publish(message []byte, queue string) error
-	Consume(queue string) (<-chan []byte, error)
+	// Сообщение станет доступно потребителям не раньше, чем через delay
+	PublishDelayed(message []byte, queue string, delay time.Duration) error
+	Consume(queue string) (<-chan broker.Message, error)
 	Close() error
 }
 
 type BannerService struct {
-	CacheTTL         time.Duration
-	BannerRepository BannerRepository
-	Cache            *BannerCache
-	Queue            *BannerQueue
-	workerStopCh     chan struct{}
+	CacheTTL                  time.Duration
+	NotFoundTTL               time.Duration
+	BannerRepository          BannerRepository
+	JobRepository             JobRepository
+	DeadLetterRepository      DeadLetterRepository
+	ScheduledChangeRepository ScheduledChangeRepository
+	EventRepository           EventRepository
+	Cache                     *BannerCache
+	Snapshot                  *BannerSnapshot
+	LastKnownGood             *LastKnownGood
+	Queue                     *BannerQueue
+	Events                    *EventBuffer
+	LocaleFallback            []string
+	workerStopCh              chan struct{}
+	loadGroup                 singleflight.Group
+	dbBreaker                 *breaker.Breaker
+	logger                    logger.Logger
 }
 
-func NewBannerService(cfg config.BannerService, bannerRepository BannerRepository, cache Cache, queue Queue, logger logger.Logger) *BannerService {
+func NewBannerService(
+	cfg config.BannerService,
+	bannerRepository BannerRepository,
+	jobRepository JobRepository,
+	deadLetterRepository DeadLetterRepository,
+	scheduledChangeRepository ScheduledChangeRepository,
+	eventRepository EventRepository,
+	cache Cache,
+	queue Queue,
+	logger logger.Logger,
+) *BannerService {
+	bannerCache := NewBannerCache(cache, cfg.LocalCacheSize, cfg.LocalCacheTTL)
 	bannerQueue := NewBannerQueue(queue, cfg.QueueName)
-	worker, workerStopCh := NewDeleteBannerWorker(bannerRepository, bannerQueue, logger)
+	retryPolicy := RetryPolicy{
+		MaxAttempts: cfg.DeleteAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+	}
+	worker, workerStopCh := NewDeleteBannerWorker(
+		bannerRepository, jobRepository, deadLetterRepository, bannerCache, bannerQueue, cfg.DeleteBatchSize, retryPolicy, logger,
+	)
 
 	for i := 0; i < cfg.DeleteWorkersNum; i++ {
 		go runWorker(context.Background(), worker, workerStopCh, logger)
 	}
 
-	return &BannerService{
-		CacheTTL:         cfg.CacheTTL,
-		BannerRepository: bannerRepository,
-		Cache:            NewBannerCache(cache),
-		Queue:            bannerQueue,
-		workerStopCh:     workerStopCh,
+	var snapshot *BannerSnapshot
+	if cfg.SnapshotEnabled {
+		snapshot = NewBannerSnapshot(bannerRepository, cfg.SnapshotRefreshInterval, logger)
+		go snapshot.Run(workerStopCh)
+	}
+
+	var lastKnownGood *LastKnownGood
+	if cfg.LastKnownGoodPath != "" {
+		var err error
+		lastKnownGood, err = NewLastKnownGood(cfg.LastKnownGoodPath)
+		if err != nil {
+			logger.Error("error loading last known good banners", slog.String("error", err.Error()))
+		}
+		go runFlusher(lastKnownGood, cfg.LastKnownGoodFlushInterval, workerStopCh, logger)
+	}
+
+	events := NewEventBuffer(eventRepository, cfg.EventsBatchSize, cfg.EventsBufferSize, logger)
+	go events.Run(cfg.EventsFlushInterval, workerStopCh)
+
+	dbBreaker := breaker.New(cfg.DBBreakerMaxFailures, cfg.DBBreakerOpenTimeout)
+	dbBreaker.IsFailure = func(err error) bool {
+		return !errors.Is(err, errs.ErrNotFound)
+	}
+	dbBreaker.OnStateChange = func(from, to breaker.State) {
+		logger.Warn("database circuit breaker state changed", slog.String("from", from.String()), slog.String("to", to.String()))
+	}
+
+	s := &BannerService{
+		CacheTTL:                  cfg.CacheTTL,
+		NotFoundTTL:               cfg.NotFoundTTL,
+		BannerRepository:          bannerRepository,
+		JobRepository:             jobRepository,
+		DeadLetterRepository:      deadLetterRepository,
+		ScheduledChangeRepository: scheduledChangeRepository,
+		EventRepository:           eventRepository,
+		Cache:                     bannerCache,
+		Snapshot:                  snapshot,
+		LastKnownGood:             lastKnownGood,
+		Queue:                     bannerQueue,
+		Events:                    events,
+		LocaleFallback:            cfg.LocaleFallback,
+		workerStopCh:              workerStopCh,
+		dbBreaker:                 dbBreaker,
+		logger:                    logger,
+	}
+
+	// Планировщик отключается при нулевом интервале опроса
+	if cfg.ScheduledChangesPollInterval > 0 {
+		scheduler := NewChangeScheduler(
+			s, scheduledChangeRepository, cfg.ScheduledChangesPollInterval, cfg.ScheduledChangesLockTimeout, cfg.ScheduledChangesBatchSize, logger,
+		)
+		go scheduler.Run(workerStopCh)
 	}
+
+	return s
 }
 
 func (s *BannerService) Shutdown() {
 	close(s.workerStopCh)
+
+	// Накопленные события дописываются при остановке, чтобы не потерять их при деплое
+	err := s.Events.Flush(context.Background())
+	if err != nil {
+		s.logger.Error("error writing banner events", slog.String("error", err.Error()))
+	}
+
+	if s.LastKnownGood != nil {
+		err = s.LastKnownGood.Flush()
+		if err != nil {
+			s.logger.Error("error saving last known good banners", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func runFlusher(lastKnownGood *LastKnownGood, interval time.Duration, stopCh chan struct{}, logger logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			err := lastKnownGood.Flush()
+			if err != nil {
+				logger.Error("error saving last known good banners", slog.String("error", err.Error()))
+			}
+		}
+	}
 }
 
 func runWorker(ctx context.Context, worker *DeleteBannerWorker, stopCh chan struct{}, logger logger.Logger) {
@@ -79,27 +221,181 @@ func runWorker(ctx context.Context, worker *DeleteBannerWorker, stopCh chan stru
 	}
 }
 
-func (s *BannerService) GetBanner(tagID, featureID int64, useLastRevision bool, onlyActive bool) (models.Banner, error) {
+// Сброс кэша по изменениям баннеров, сделанным другими инстансами.
+// Блокируется до отмены ctx, после разрыва соединения подписка восстанавливается через reconnectDelay.
+// Изменения, пришедшие во время разрыва, теряются, такие записи истекут через CacheTTL
+func (s *BannerService) ListenBannerChanges(ctx context.Context, source BannerChangeSource, reconnectDelay time.Duration) {
+	for {
+		err := source.ListenBannerChanges(ctx, s.applyBannerChange)
+		if err != nil {
+			s.logger.Error("error listening banner changes", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (s *BannerService) applyBannerChange(change models.BannerChange) {
+	err := s.Cache.RemoveChange(change)
+	if err != nil {
+		s.logger.Error("error invalidating banner cache", slog.Int64("banner_id", change.BannerID), slog.String("error", err.Error()))
+	}
+	s.applySnapshot(change)
+}
+
+// При ошибке снимок остаётся прежним до следующей полной перезагрузки.
+// Из последнего сохранённого состояния изменённый баннер убирается, чтобы при отказе базы не отдать устаревшую версию
+func (s *BannerService) applySnapshot(change models.BannerChange) {
+	if s.LastKnownGood != nil {
+		s.LastKnownGood.Remove(change)
+	}
+	if s.Snapshot == nil {
+		return
+	}
+
+	err := s.Snapshot.Apply(context.TODO(), change)
+	if err != nil {
+		s.logger.Error("error updating banner snapshot", slog.Int64("banner_id", change.BannerID), slog.String("error", err.Error()))
+	}
+}
+
+// Сколько клиент может хранить баннер, не перепрашивая его: столько же, сколько он лежит в кэше,
+// но не дольше ближайшей границы окна показа, после которой ответ изменится
+func (s *BannerService) CacheMaxAge(banner models.Banner) time.Duration {
+	maxAge := s.CacheTTL
+	if boundary, ok := banner.NextBoundary(time.Now()); ok {
+		if untilBoundary := time.Until(boundary); untilBoundary < maxAge {
+			maxAge = untilBoundary
+		}
+	}
+	return maxAge
+}
+
+func (s *BannerService) CacheStats() memory.LRUStats {
+	return s.Cache.LocalStats()
+}
+
+// В кэше лежит админское представление баннера (независимо от активности) под ключом тег-фича,
+// поэтому одна запись обслуживает и админов, и пользователей. Выключенные баннеры и баннеры
+// вне окна показа отсекаются при чтении, и пользователь никогда не получит их из кэша.
+// Если база недоступна, баннер отдаётся из последнего сохранённого состояния с degraded = true
+func (s *BannerService) GetBanner(tagID, featureID int64, useLastRevision bool, onlyActive bool) (banner models.Banner, degraded bool, err error) {
 	if useLastRevision {
-		banner, err := s.BannerRepository.GetBanner(context.Background(), tagID, featureID, onlyActive)
+		banner, err := s.loadBanner(tagID, featureID)
+		go s.fillCache(tagID, featureID, banner, err)
 		if err != nil {
-			return models.Banner{}, err
+			return models.Banner{}, false, err
 		}
-		go s.Cache.Push(banner, s.CacheTTL)
-		return banner, nil
+		s.recordLastKnownGood(banner)
+		banner, err = filterActive(banner, onlyActive)
+		return banner, false, err
 	}
 
-	banner, err := s.Cache.Get(tagID, featureID)
+	if s.Snapshot != nil {
+		banner, found, ok := s.Snapshot.Get(tagID, featureID)
+		if ok {
+			if !found {
+				return models.Banner{}, false, errs.Wrap("BannerService.GetBanner", "banner not found in snapshot", errs.ErrNotFound)
+			}
+			s.recordLastKnownGood(banner)
+			banner, err = filterActive(banner, onlyActive)
+			return banner, false, err
+		}
+	}
+
+	banner, err = s.Cache.Get(tagID, featureID)
 	if err == nil {
+		s.recordLastKnownGood(banner)
+		banner, err = filterActive(banner, onlyActive)
+		return banner, false, err
+	}
+	if errors.Is(err, ErrCachedNotFound) {
+		return models.Banner{}, false, err
+	}
+
+	// Одновременные промахи по одному ключу схлопываются в один запрос к базе и одно заполнение кэша
+	key := cacheKey(tagID, featureID)
+	result, err, _ := s.loadGroup.Do(key, func() (any, error) {
+		banner, err := s.loadBanner(tagID, featureID)
+
+		// Кэш заполняется до выхода из группы, чтобы опоздавшие запросы уже попали в него
+		s.fillCache(tagID, featureID, banner, err)
+		if err != nil {
+			return models.Banner{}, err
+		}
 		return banner, nil
+	})
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) || s.LastKnownGood == nil {
+			return models.Banner{}, false, err
+		}
+
+		// База недоступна: отдаём то, что отдавали до отказа
+		lastBanner, ok := s.LastKnownGood.Get(tagID, featureID)
+		if !ok {
+			return models.Banner{}, false, err
+		}
+		s.logger.Warn("serving last known good banner", slog.Int64("banner_id", lastBanner.ID), slog.String("error", err.Error()))
+		banner, err = filterActive(lastBanner, onlyActive)
+		return banner, true, err
 	}
 
-	banner, err = s.BannerRepository.GetBanner(context.TODO(), tagID, featureID, onlyActive)
+	banner = result.(models.Banner)
+	s.recordLastKnownGood(banner)
+	banner, err = filterActive(banner, onlyActive)
+	return banner, false, err
+}
+
+// Чтение баннера из базы через circuit breaker: пока он открыт, база не дёргается
+func (s *BannerService) loadBanner(tagID, featureID int64) (models.Banner, error) {
+	var banner models.Banner
+	err := s.dbBreaker.Do(func() error {
+		var err error
+		banner, err = s.BannerRepository.GetBanner(context.TODO(), tagID, featureID, false)
+		return err
+	})
+	return banner, err
+}
+
+func (s *BannerService) recordLastKnownGood(banner models.Banner) {
+	if s.LastKnownGood != nil {
+		s.LastKnownGood.Record(banner)
+	}
+}
+
+// Сохранение результата чтения из базы: баннера или, на короткий NotFoundTTL, его отсутствия
+func (s *BannerService) fillCache(tagID, featureID int64, banner models.Banner, loadErr error) {
+	var err error
+	switch {
+	case loadErr == nil:
+		err = s.Cache.Push(banner, s.CacheTTL)
+	case errors.Is(loadErr, errs.ErrNotFound) && s.NotFoundTTL > 0:
+		err = s.Cache.PushNotFound(tagID, featureID, s.NotFoundTTL)
+	default:
+		return
+	}
+
+	// Кэш недоступен, breaker уже сообщил об этом
+	if errors.Is(err, breaker.ErrOpen) {
+		return
+	}
 	if err != nil {
-		return models.Banner{}, err
+		s.logger.Error("error caching banner",
+			slog.Int64("tag_id", tagID),
+			slog.Int64("feature_id", featureID),
+			slog.String("error", err.Error()),
+		)
 	}
+}
 
-	go s.Cache.Push(banner, s.CacheTTL)
+func filterActive(banner models.Banner, onlyActive bool) (models.Banner, error) {
+	if onlyActive && !banner.VisibleAt(time.Now()) {
+		return models.Banner{}, errs.Wrap("BannerService.GetBanner", "banner is disabled", errs.ErrNotFound)
+	}
 	return banner, nil
 }
 
@@ -107,12 +403,45 @@ func (s *BannerService) ListBanners(featureID, tagID n.NullInt64, limit, offset
 	return s.BannerRepository.ListBanners(context.TODO(), featureID, tagID, limit, offset)
 }
 
-func (s *BannerService) CreateBanner(tagIDs []int64, featureID int64, content json.RawMessage, isActive bool) (int64, error) {
-	return s.BannerRepository.CreateBanner(context.TODO(), tagIDs, featureID, content, isActive)
+func (s *BannerService) CreateBanner(input models.BannerInput) (int64, error) {
+	bannerID, err := s.BannerRepository.CreateBanner(context.TODO(), input)
+	if err != nil {
+		return 0, err
+	}
+
+	s.invalidate(models.Banner{ID: bannerID, FeatureID: input.FeatureID, TagIds: input.TagIDs})
+	return bannerID, nil
+}
+
+// Обновление баннера с проверкой ревизии, возвращает новую ревизию.
+// Если expectedRevision не задана, баннер обновляется без проверки.
+// Нулевая граница окна показа снимает её, переданные варианты заменяют прежние
+func (s *BannerService) UpdateBanner(bannerID int64, expectedRevision n.NullInt64, patch models.BannerPatch) (int64, error) {
+	// Старые теги и фича нужны, чтобы убрать из кэша ключи, которые после обновления баннеру уже не принадлежат
+	before, err := s.BannerRepository.GetBannerByID(context.TODO(), bannerID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return 0, err
+	}
+
+	revision, err := s.BannerRepository.UpdateBanner(context.TODO(), bannerID, expectedRevision, patch)
+	if err != nil {
+		return 0, err
+	}
+
+	after := before
+	if len(patch.TagIDs) > 0 {
+		after.TagIds = patch.TagIDs
+	}
+	if patch.FeatureID.Valid {
+		after.FeatureID = patch.FeatureID.Int64
+	}
+
+	s.invalidate(before, after)
+	return revision, nil
 }
 
-func (s *BannerService) UpdateBanner(bannerID int64, tagIDs []int64, featureID n.NullInt64, content json.RawMessage, isActive n.NullBool) error {
-	return s.BannerRepository.UpdateBanner(context.TODO(), bannerID, tagIDs, featureID, content, isActive)
+func (s *BannerService) GetBannerByID(bannerID int64) (models.Banner, error) {
+	return s.BannerRepository.GetBannerByID(context.TODO(), bannerID)
 }
 
 func (s *BannerService) ListBannerVersions(bannerID int64) ([]models.BannerVersion, error) {
@@ -120,21 +449,122 @@ func (s *BannerService) ListBannerVersions(bannerID int64) ([]models.BannerVersi
 }
 
 func (s *BannerService) RestoreVersion(bannerID int64, updatedAt models.UnixTime) error {
-	return s.BannerRepository.RestoreVersion(context.TODO(), bannerID, updatedAt)
+	err := s.BannerRepository.RestoreVersion(context.TODO(), bannerID, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	banner, err := s.BannerRepository.GetBannerByID(context.TODO(), bannerID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	s.invalidate(banner)
+	return nil
 }
 
-func (s *BannerService) DeleteBanner(bannerID int64) error {
+func (s *BannerService) DeleteBanner(bannerID int64) (int64, error) {
 	_, err := s.BannerRepository.GetBannerByID(context.Background(), bannerID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.enqueueDeletion(DeleteBannerFilter{BannerID: n.NullInt64From(bannerID)})
+}
+
+func (s *BannerService) DeleteBanners(featureID, tagID n.NullInt64) (int64, error) {
+	if !featureID.Valid && !tagID.Valid {
+		return 0, errs.ErrRequiredValue
+	}
+
+	return s.enqueueDeletion(DeleteBannerFilter{FeatureID: featureID, TagID: tagID})
+}
+
+func (s *BannerService) GetJob(jobID int64) (models.Job, error) {
+	return s.JobRepository.GetJob(context.TODO(), jobID)
+}
+
+func (s *BannerService) ListDeadLetters(limit, offset n.NullUint64) ([]models.DeadLetter, error) {
+	return s.DeadLetterRepository.ListDeadLetters(context.TODO(), limit, offset)
+}
+
+// Повторная постановка задачи из dead letter queue в очередь с обнулённым счётчиком попыток.
+// Запись сначала забирается из dead letter queue, поэтому два одновременных повтора не поставят задачу дважды.
+// Если поставить задачу не удалось, запись возвращается обратно под новым id
+func (s *BannerService) ReplayDeadLetter(id int64) error {
+	const op = "BannerService.ReplayDeadLetter"
+
+	deadLetter, err := s.DeadLetterRepository.TakeDeadLetter(context.TODO(), id)
 	if err != nil {
 		return err
 	}
 
-	task := &DeleteBannerTask{
-		ID: bannerID,
+	var task DeleteBannerTask
+	if err := json.Unmarshal(deadLetter.Task, &task); err != nil {
+		s.returnDeadLetter(deadLetter)
+		return errs.Wrap(op, "failed to decode task", err)
 	}
-	err = s.Queue.Publish(task)
+	task.Attempt = 0
+
+	err = s.JobRepository.UpdateJobStatus(context.TODO(), task.JobID, models.JobQueued, "")
+	if err != nil {
+		s.returnDeadLetter(deadLetter)
+		return err
+	}
+
+	err = s.Queue.Publish(&task)
 	if err != nil {
+		s.JobRepository.UpdateJobStatus(context.TODO(), task.JobID, models.JobFailed, err.Error())
+		s.returnDeadLetter(deadLetter)
 		return err
 	}
+
 	return nil
 }
+
+func (s *BannerService) returnDeadLetter(deadLetter models.DeadLetter) {
+	_, err := s.DeadLetterRepository.AddDeadLetter(context.TODO(), deadLetter.JobID, deadLetter.Task, deadLetter.Error, deadLetter.Attempts)
+	if err != nil {
+		s.logger.Error("failed to return dead letter", slog.Int64("job_id", deadLetter.JobID), slog.String("error", err.Error()))
+	}
+}
+
+func (s *BannerService) DiscardDeadLetter(id int64) error {
+	return s.DeadLetterRepository.DeleteDeadLetter(context.TODO(), id)
+}
+
+// Создание задачи на удаление и публикация её в очередь, возвращает идентификатор задачи
+func (s *BannerService) enqueueDeletion(filter DeleteBannerFilter) (int64, error) {
+	jobID, err := s.JobRepository.CreateJob(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+
+	task := &DeleteBannerTask{
+		JobID:  jobID,
+		Filter: filter,
+	}
+	err = s.Queue.Publish(task)
+	if err != nil {
+		s.JobRepository.UpdateJobStatus(context.TODO(), jobID, models.JobFailed, err.Error())
+		return 0, err
+	}
+
+	return jobID, nil
+}
+
+// Удаление из кэша ключей переданных баннеров. Изменение в базе к этому моменту уже закоммичено,
+// поэтому ошибка кэша только логируется: запись в любом случае истечёт через CacheTTL
+func (s *BannerService) invalidate(banners ...models.Banner) {
+	for _, banner := range banners {
+		err := s.Cache.Remove(banner)
+		if err != nil {
+			s.logger.Error("error invalidating banner cache", slog.Int64("banner_id", banner.ID), slog.String("error", err.Error()))
+		}
+		// Свои изменения видны в снимке сразу, не дожидаясь уведомления из базы
+		s.applySnapshot(models.BannerChange{BannerID: banner.ID, FeatureIDs: []int64{banner.FeatureID}, TagIDs: banner.TagIds})
+	}
+}
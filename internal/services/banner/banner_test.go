@@ -0,0 +1,955 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"backend-trainee-assignment-2024/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Репозиторий баннеров в памяти, чтобы проверять сервис без Postgres
+type fakeBannerRepository struct {
+	mu        sync.Mutex
+	banners   map[int64]models.Banner
+	nextID    int64
+	deleteErr error
+	listErr   error
+	getErr    error
+
+	getBannerCalls  atomic.Int64
+	getBannersCalls atomic.Int64
+	// Если задан, GetBanner ждёт его закрытия, чтобы запросы успели пересечься
+	getBannerGate chan struct{}
+}
+
+func newFakeBannerRepository(banners ...models.Banner) *fakeBannerRepository {
+	r := &fakeBannerRepository{banners: make(map[int64]models.Banner)}
+	for _, banner := range banners {
+		r.banners[banner.ID] = banner
+		if banner.ID > r.nextID {
+			r.nextID = banner.ID
+		}
+	}
+	return r
+}
+
+func hasTag(banner models.Banner, tagID int64) bool {
+	for _, id := range banner.TagIds {
+		if id == tagID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fakeBannerRepository) GetBanner(ctx context.Context, tagID, featureID int64, onlyActive bool) (models.Banner, error) {
+	r.getBannerCalls.Add(1)
+	if r.getBannerGate != nil {
+		<-r.getBannerGate
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.getErr != nil {
+		return models.Banner{}, r.getErr
+	}
+
+	for _, banner := range r.banners {
+		if banner.FeatureID == featureID && hasTag(banner, tagID) && (banner.IsActive || !onlyActive) {
+			return banner, nil
+		}
+	}
+	return models.Banner{}, errs.ErrNotFound
+}
+
+func (r *fakeBannerRepository) GetBanners(ctx context.Context, keys []models.BannerKey) ([]models.Banner, error) {
+	r.getBannersCalls.Add(1)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.getErr != nil {
+		return nil, r.getErr
+	}
+
+	var banners []models.Banner
+	for _, key := range keys {
+		for _, banner := range r.banners {
+			if banner.FeatureID == key.FeatureID && hasTag(banner, key.TagID) {
+				banners = append(banners, banner)
+			}
+		}
+	}
+	return banners, nil
+}
+
+func (r *fakeBannerRepository) GetBannerByID(ctx context.Context, id int64) (models.Banner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	banner, ok := r.banners[id]
+	if !ok {
+		return models.Banner{}, errs.ErrNotFound
+	}
+	return banner, nil
+}
+
+func (r *fakeBannerRepository) ListBanners(ctx context.Context, featureID, tagID n.NullInt64, limit, offset n.NullUint64) ([]models.Banner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.listErr != nil {
+		return nil, r.listErr
+	}
+
+	var banners []models.Banner
+	for _, banner := range r.banners {
+		if featureID.Valid && banner.FeatureID != featureID.Int64 {
+			continue
+		}
+		if tagID.Valid && !hasTag(banner, tagID.Int64) {
+			continue
+		}
+		banners = append(banners, banner)
+	}
+	return banners, nil
+}
+
+// Собирает баннер из входных данных так же, как это делает репозиторий
+func bannerFromInput(id int64, input models.BannerInput) models.Banner {
+	return models.Banner{
+		ID:               id,
+		Content:          input.Content,
+		IsActive:         input.IsActive,
+		FeatureID:        input.FeatureID,
+		TagIds:           input.TagIDs,
+		Variants:         input.Variants,
+		FrequencyCap:     input.FrequencyCap,
+		ActiveWindow:     input.ActiveWindow,
+		LocalizedContent: input.LocalizedContent,
+	}
+}
+
+// Применяет к баннеру только переданные поля патча
+func applyPatch(banner *models.Banner, patch models.BannerPatch) {
+	if len(patch.TagIDs) > 0 {
+		banner.TagIds = patch.TagIDs
+	}
+	if patch.FeatureID.Valid {
+		banner.FeatureID = patch.FeatureID.Int64
+	}
+	if patch.Content != nil {
+		banner.Content = patch.Content
+	}
+	if patch.IsActive.Valid {
+		banner.IsActive = patch.IsActive.Bool
+	}
+	if patch.ActiveFrom.Valid {
+		banner.ActiveFrom = patch.ActiveFrom
+	}
+	if patch.ActiveUntil.Valid {
+		banner.ActiveUntil = patch.ActiveUntil
+	}
+	if patch.Variants != nil {
+		banner.Variants = patch.Variants
+	}
+	if patch.FrequencyCap != nil {
+		banner.FrequencyCap = patch.FrequencyCap
+		if patch.FrequencyCap.IsZero() {
+			banner.FrequencyCap = nil
+		}
+	}
+	if len(patch.LocalizedContent) > 0 {
+		merged := make(models.LocalizedContent, len(banner.LocalizedContent))
+		for locale, localized := range banner.LocalizedContent {
+			merged[locale] = localized
+		}
+		for locale, localized := range patch.LocalizedContent {
+			if string(localized) == "null" {
+				delete(merged, locale)
+				continue
+			}
+			merged[locale] = localized
+		}
+		banner.LocalizedContent = merged
+	}
+}
+
+func (r *fakeBannerRepository) CreateBanner(ctx context.Context, input models.BannerInput) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	r.banners[r.nextID] = bannerFromInput(r.nextID, input)
+	return r.nextID, nil
+}
+
+func (r *fakeBannerRepository) UpdateBanner(ctx context.Context, bannerID int64, expectedRevision n.NullInt64, patch models.BannerPatch) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	banner, ok := r.banners[bannerID]
+	if !ok {
+		return 0, errs.ErrNotFound
+	}
+	if expectedRevision.Valid && expectedRevision.Int64 != banner.Revision {
+		return 0, errs.ErrRevisionMismatch
+	}
+	applyPatch(&banner, patch)
+	banner.Revision++
+	r.banners[bannerID] = banner
+	return banner.Revision, nil
+}
+
+func (r *fakeBannerRepository) DeleteBanner(ctx context.Context, bannerID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.deleteErr != nil {
+		return r.deleteErr
+	}
+	if _, ok := r.banners[bannerID]; !ok {
+		return errs.ErrNotFound
+	}
+	delete(r.banners, bannerID)
+	return nil
+}
+
+func (r *fakeBannerRepository) DeleteBanners(ctx context.Context, featureID, tagID n.NullInt64, limit uint64) ([]models.Banner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.deleteErr != nil {
+		return nil, r.deleteErr
+	}
+
+	var deleted []models.Banner
+	for id, banner := range r.banners {
+		if uint64(len(deleted)) == limit {
+			break
+		}
+		if featureID.Valid && banner.FeatureID != featureID.Int64 {
+			continue
+		}
+		if tagID.Valid && !hasTag(banner, tagID.Int64) {
+			continue
+		}
+		delete(r.banners, id)
+		deleted = append(deleted, banner)
+	}
+	return deleted, nil
+}
+
+func (r *fakeBannerRepository) ListBannerVersions(ctx context.Context, bannerID int64) ([]models.BannerVersion, error) {
+	return nil, nil
+}
+
+func (r *fakeBannerRepository) RestoreVersion(ctx context.Context, bannerID int64, updatedAt models.UnixTime) error {
+	return nil
+}
+
+type fakeJobRepository struct {
+	mu     sync.Mutex
+	jobs   map[int64]models.Job
+	nextID int64
+}
+
+func newFakeJobRepository() *fakeJobRepository {
+	return &fakeJobRepository{jobs: make(map[int64]models.Job)}
+}
+
+func (r *fakeJobRepository) CreateJob(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	r.jobs[r.nextID] = models.Job{ID: r.nextID, Status: models.JobQueued}
+	return r.nextID, nil
+}
+
+func (r *fakeJobRepository) UpdateJobStatus(ctx context.Context, id int64, status models.JobStatus, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return errs.ErrNotFound
+	}
+	job.Status = status
+	job.Error = errMsg
+	r.jobs[id] = job
+	return nil
+}
+
+func (r *fakeJobRepository) GetJob(ctx context.Context, id int64) (models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return models.Job{}, errs.ErrNotFound
+	}
+	return job, nil
+}
+
+type fakeDeadLetterRepository struct {
+	mu          sync.Mutex
+	deadLetters map[int64]models.DeadLetter
+	nextID      int64
+}
+
+func newFakeDeadLetterRepository() *fakeDeadLetterRepository {
+	return &fakeDeadLetterRepository{deadLetters: make(map[int64]models.DeadLetter)}
+}
+
+func (r *fakeDeadLetterRepository) AddDeadLetter(ctx context.Context, jobID int64, task json.RawMessage, errMsg string, attempts int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	r.deadLetters[r.nextID] = models.DeadLetter{ID: r.nextID, JobID: jobID, Task: task, Error: errMsg, Attempts: attempts}
+	return r.nextID, nil
+}
+
+func (r *fakeDeadLetterRepository) TakeDeadLetter(ctx context.Context, id int64) (models.DeadLetter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deadLetter, ok := r.deadLetters[id]
+	if !ok {
+		return models.DeadLetter{}, errs.ErrNotFound
+	}
+	delete(r.deadLetters, id)
+	return deadLetter, nil
+}
+
+func (r *fakeDeadLetterRepository) ListDeadLetters(ctx context.Context, limit, offset n.NullUint64) ([]models.DeadLetter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deadLetters []models.DeadLetter
+	for _, deadLetter := range r.deadLetters {
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
+
+func (r *fakeDeadLetterRepository) DeleteDeadLetter(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deadLetters[id]; !ok {
+		return errs.ErrNotFound
+	}
+	delete(r.deadLetters, id)
+	return nil
+}
+
+type fakeScheduledChangeRepository struct {
+	mu      sync.Mutex
+	changes map[int64]models.ScheduledChange
+	claimed map[int64]bool
+	nextID  int64
+}
+
+func newFakeScheduledChangeRepository() *fakeScheduledChangeRepository {
+	return &fakeScheduledChangeRepository{
+		changes: make(map[int64]models.ScheduledChange),
+		claimed: make(map[int64]bool),
+	}
+}
+
+func (r *fakeScheduledChangeRepository) CreateScheduledChange(ctx context.Context, bannerID int64, applyAt models.UnixTime, change models.BannerPatch) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	r.changes[r.nextID] = models.ScheduledChange{
+		ID:       r.nextID,
+		BannerID: bannerID,
+		Change:   change,
+		ApplyAt:  applyAt,
+		Status:   models.ScheduledChangePending,
+	}
+	return r.nextID, nil
+}
+
+func (r *fakeScheduledChangeRepository) ListScheduledChanges(ctx context.Context, bannerID int64) ([]models.ScheduledChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := make([]models.ScheduledChange, 0)
+	for id := int64(1); id <= r.nextID; id++ {
+		change, ok := r.changes[id]
+		if ok && change.BannerID == bannerID && change.Status == models.ScheduledChangePending {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (r *fakeScheduledChangeRepository) CancelScheduledChange(ctx context.Context, bannerID, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change, ok := r.changes[id]
+	if !ok || change.BannerID != bannerID || change.Status != models.ScheduledChangePending {
+		return errs.ErrNotFound
+	}
+	change.Status = models.ScheduledChangeCancelled
+	r.changes[id] = change
+	return nil
+}
+
+func (r *fakeScheduledChangeRepository) ClaimDueScheduledChanges(ctx context.Context, limit uint64, lockTimeout time.Duration) ([]models.ScheduledChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := models.UnixTime(time.Now().Unix())
+	var changes []models.ScheduledChange
+	for id := int64(1); id <= r.nextID && uint64(len(changes)) < limit; id++ {
+		change, ok := r.changes[id]
+		if ok && change.Status == models.ScheduledChangePending && change.ApplyAt <= now && !r.claimed[id] {
+			r.claimed[id] = true
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (r *fakeScheduledChangeRepository) FinishScheduledChange(ctx context.Context, id int64, status models.ScheduledChangeStatus, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change, ok := r.changes[id]
+	if !ok {
+		return errs.ErrNotFound
+	}
+	change.Status = status
+	change.Error = errMsg
+	r.changes[id] = change
+	delete(r.claimed, id)
+	return nil
+}
+
+func (r *fakeScheduledChangeRepository) get(id int64) models.ScheduledChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.changes[id]
+}
+
+type fakeEventRepository struct {
+	mu      sync.Mutex
+	events  []models.Event
+	batches int
+	err     error
+}
+
+func newFakeEventRepository() *fakeEventRepository {
+	return &fakeEventRepository{}
+}
+
+func (r *fakeEventRepository) InsertEvents(ctx context.Context, events []models.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, events...)
+	r.batches++
+	return nil
+}
+
+func (r *fakeEventRepository) GetBannerStats(ctx context.Context, bannerID int64, from time.Time) ([]models.DailyStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]models.DailyStats, 0)
+	for _, event := range r.events {
+		if event.BannerID != bannerID || event.CreatedAt.Before(from) {
+			continue
+		}
+		day := event.CreatedAt.UTC().Format(time.DateOnly)
+		if len(stats) == 0 || stats[len(stats)-1].Day != day {
+			stats = append(stats, models.DailyStats{Day: day})
+		}
+		switch event.Type {
+		case models.EventImpression:
+			stats[len(stats)-1].Impressions++
+		case models.EventClick:
+			stats[len(stats)-1].Clicks++
+		}
+		stats[len(stats)-1].UpdateCTR()
+	}
+	return stats, nil
+}
+
+func (r *fakeEventRepository) setErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+}
+
+func (r *fakeEventRepository) written() ([]models.Event, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]models.Event(nil), r.events...), r.batches
+}
+
+type testService struct {
+	*BannerService
+	bannerRepository          *fakeBannerRepository
+	jobRepository             *fakeJobRepository
+	deadLetterRepository      *fakeDeadLetterRepository
+	scheduledChangeRepository *fakeScheduledChangeRepository
+	eventRepository           *fakeEventRepository
+	cache                     *memory.Cache
+}
+
+func newTestService(t *testing.T, banners ...models.Banner) *testService {
+	return newTestServiceWithConfig(t, nil, banners...)
+}
+
+func newTestServiceWithConfig(t *testing.T, configure func(cfg *config.BannerService), banners ...models.Banner) *testService {
+	cfg := config.BannerService{
+		CacheTTL:            time.Minute,
+		NotFoundTTL:         time.Minute,
+		DeleteWorkersNum:    2,
+		DeleteBatchSize:     2,
+		DeleteAttempts:      3,
+		RetryBaseDelay:      time.Millisecond,
+		RetryMaxDelay:       10 * time.Millisecond,
+		QueueName:           "test_queue",
+		LocalCacheSize:      100,
+		LocalCacheTTL:       time.Minute,
+		EventsFlushInterval: time.Minute,
+		EventsBatchSize:     100,
+		EventsBufferSize:    1000,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	ts := &testService{
+		bannerRepository:          newFakeBannerRepository(banners...),
+		jobRepository:             newFakeJobRepository(),
+		deadLetterRepository:      newFakeDeadLetterRepository(),
+		scheduledChangeRepository: newFakeScheduledChangeRepository(),
+		eventRepository:           newFakeEventRepository(),
+		cache:                     memory.NewCache(time.Minute),
+	}
+	queue := memory.NewQueue(100)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ts.BannerService = NewBannerService(cfg, ts.bannerRepository, ts.jobRepository, ts.deadLetterRepository, ts.scheduledChangeRepository, ts.eventRepository, ts.cache, queue, logger)
+
+	t.Cleanup(func() {
+		ts.Shutdown()
+		queue.Close()
+		ts.cache.Close()
+	})
+
+	return ts
+}
+
+// Ожидание завершения отложенной задачи
+func waitJob(t *testing.T, s *testService, jobID int64) models.Job {
+	var job models.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = s.GetJob(jobID)
+		require.NoError(t, err)
+		return job.Status == models.JobDone || job.Status == models.JobFailed
+	}, 3*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestBannerService_DeleteBanner(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 20, TagIds: []int64{100}},
+	)
+
+	jobID, err := s.DeleteBanner(1)
+	require.NoError(t, err)
+
+	job := waitJob(t, s, jobID)
+	assert.Equal(t, models.JobDone, job.Status)
+
+	_, err = s.BannerRepository.GetBannerByID(context.Background(), 1)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	_, err = s.DeleteBanner(1)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestBannerService_InvalidateCache(t *testing.T) {
+	banner := models.Banner{ID: 1, Content: json.RawMessage(`{"title":"old"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100, 200}}
+	s := newTestService(t, banner,
+		models.Banner{ID: 2, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 30, TagIds: []int64{300}},
+	)
+
+	cached := func(tagID, featureID int64) bool {
+		_, err := s.Cache.Get(tagID, featureID)
+		return err == nil
+	}
+
+	t.Run("update evicts old and new keys", func(t *testing.T) {
+		require.NoError(t, s.Cache.Push(banner, time.Minute))
+
+		_, err := s.UpdateBanner(1, n.NullInt64{}, models.BannerPatch{TagIDs: []int64{200, 400}, FeatureID: n.NullInt64From(20), Content: json.RawMessage(`{"title":"new"}`)})
+		require.NoError(t, err)
+
+		assert.False(t, cached(100, 10))
+		assert.False(t, cached(200, 10))
+
+		assert.False(t, cached(400, 20))
+	})
+
+	t.Run("restore evicts current keys", func(t *testing.T) {
+		banner, err := s.BannerRepository.GetBannerByID(context.Background(), 1)
+		require.NoError(t, err)
+		require.NoError(t, s.Cache.Push(banner, time.Minute))
+
+		require.NoError(t, s.RestoreVersion(1, 0))
+
+		assert.False(t, cached(200, 20))
+		assert.False(t, cached(400, 20))
+	})
+
+	t.Run("delete evicts keys", func(t *testing.T) {
+		banner, err := s.BannerRepository.GetBannerByID(context.Background(), 2)
+		require.NoError(t, err)
+		require.NoError(t, s.Cache.Push(banner, time.Minute))
+
+		jobID, err := s.DeleteBanners(n.NullInt64From(30), n.NullInt64{})
+		require.NoError(t, err)
+		waitJob(t, s, jobID)
+
+		assert.False(t, cached(300, 30))
+		_, _, err = s.GetBanner(300, 30, false, true)
+		assert.ErrorIs(t, err, errs.ErrNotFound)
+	})
+}
+
+func TestBannerService_DeleteBanners(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), FeatureID: 10, TagIds: []int64{100}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{}`), FeatureID: 10, TagIds: []int64{200}},
+		models.Banner{ID: 3, Content: json.RawMessage(`{}`), FeatureID: 10, TagIds: []int64{300}},
+		models.Banner{ID: 4, Content: json.RawMessage(`{}`), FeatureID: 20, TagIds: []int64{100}},
+	)
+
+	_, err := s.DeleteBanners(n.NullInt64{}, n.NullInt64{})
+	assert.ErrorIs(t, err, errs.ErrRequiredValue)
+
+	// Баннеров фичи больше, чем размер пачки
+	jobID, err := s.DeleteBanners(n.NullInt64From(10), n.NullInt64{})
+	require.NoError(t, err)
+
+	job := waitJob(t, s, jobID)
+	assert.Equal(t, models.JobDone, job.Status)
+
+	banners, err := s.ListBanners(n.NullInt64{}, n.NullInt64{}, n.NullUint64{}, n.NullUint64{})
+	require.NoError(t, err)
+	require.Len(t, banners, 1)
+	assert.Equal(t, int64(4), banners[0].ID)
+}
+
+func TestBannerService_DeleteBannerDeadLetter(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), FeatureID: 10, TagIds: []int64{100}},
+	)
+	s.bannerRepository.deleteErr = errors.New("database is down")
+
+	jobID, err := s.DeleteBanner(1)
+	require.NoError(t, err)
+
+	job := waitJob(t, s, jobID)
+	assert.Equal(t, models.JobFailed, job.Status)
+	assert.Equal(t, "database is down", job.Error)
+
+	deadLetters, err := s.ListDeadLetters(n.NullUint64{}, n.NullUint64{})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, jobID, deadLetters[0].JobID)
+	assert.Equal(t, 3, deadLetters[0].Attempts)
+
+	// После восстановления базы задачу можно выполнить повторно
+	s.bannerRepository.mu.Lock()
+	s.bannerRepository.deleteErr = nil
+	s.bannerRepository.mu.Unlock()
+
+	require.NoError(t, s.ReplayDeadLetter(deadLetters[0].ID))
+	// Повторный вызов не ставит задачу второй раз
+	assert.ErrorIs(t, s.ReplayDeadLetter(deadLetters[0].ID), errs.ErrNotFound)
+
+	job = waitJob(t, s, jobID)
+	assert.Equal(t, models.JobDone, job.Status)
+
+	deadLetters, err = s.ListDeadLetters(n.NullUint64{}, n.NullUint64{})
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+// Источник изменений, который отдаёт заранее заданные изменения и закрывает подписку
+type fakeBannerChangeSource struct {
+	changes []models.BannerChange
+}
+
+func (s *fakeBannerChangeSource) ListenBannerChanges(ctx context.Context, handle func(models.BannerChange)) error {
+	for _, change := range s.changes {
+		handle(change)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func TestBannerService_ListenBannerChanges(t *testing.T) {
+	s := newTestService(t)
+
+	banner := models.Banner{ID: 1, Content: json.RawMessage(`{}`), FeatureID: 10, TagIds: []int64{100, 200}}
+	other := models.Banner{ID: 2, Content: json.RawMessage(`{}`), FeatureID: 20, TagIds: []int64{300}}
+	require.NoError(t, s.Cache.Push(banner, time.Minute))
+	require.NoError(t, s.Cache.Push(other, time.Minute))
+
+	source := &fakeBannerChangeSource{changes: []models.BannerChange{
+		{BannerID: 1, FeatureIDs: []int64{10, 30}, TagIDs: []int64{100, 200, 400}},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.ListenBannerChanges(ctx, source, time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, err100 := s.Cache.Get(100, 10)
+		_, err200 := s.Cache.Get(200, 10)
+		return err100 != nil && err200 != nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, err := s.Cache.Get(300, 20)
+	assert.NoError(t, err)
+
+	cancel()
+	<-done
+}
+
+func TestBannerService_LocalCache(t *testing.T) {
+	s := newTestService(t)
+
+	banner := models.Banner{ID: 1, Content: json.RawMessage(`{"title":"cached"}`), FeatureID: 10, TagIds: []int64{100}}
+	require.NoError(t, s.Cache.Push(banner, time.Minute))
+
+	// Запись пропала из общего кэша, но ещё лежит в локальном
+	require.NoError(t, s.cache.Remove("100_10"))
+
+	got, err := s.Cache.Get(100, 10)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title":"cached"}`, string(got.Content))
+	assert.Equal(t, uint64(1), s.CacheStats().Hits)
+
+	// Инвалидация сбрасывает и локальный уровень
+	s.applyBannerChange(models.BannerChange{BannerID: 1, FeatureIDs: []int64{10}, TagIDs: []int64{100}})
+
+	_, err = s.Cache.Get(100, 10)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	assert.Equal(t, uint64(1), s.CacheStats().Misses)
+}
+
+func TestBannerService_GetBannerCoalescing(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{"title":"hot"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+	)
+	gate := make(chan struct{})
+	s.bannerRepository.getBannerGate = gate
+
+	const requests = 50
+	var wg sync.WaitGroup
+	results := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			banner, _, err := s.GetBanner(100, 10, false, true)
+			if err == nil && banner.ID != 1 {
+				err = errors.New("unexpected banner")
+			}
+			results <- err
+		}()
+	}
+
+	// Первый запрос дошёл до базы, остальные должны присоединиться к нему
+	require.Eventually(t, func() bool {
+		return s.bannerRepository.getBannerCalls.Load() == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(gate)
+
+	wg.Wait()
+	close(results)
+	for err := range results {
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int64(1), s.bannerRepository.getBannerCalls.Load())
+
+	// Кэш заполнен, следующий запрос в базу не идёт
+	_, _, err := s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), s.bannerRepository.getBannerCalls.Load())
+}
+
+func TestBannerService_GetBannerInactive(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: false, FeatureID: 10, TagIds: []int64{100, 200}},
+	)
+
+	// Админ видит выключенный баннер, и он попадает в кэш под всеми тегами
+	banner, _, err := s.GetBanner(100, 10, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), banner.ID)
+
+	_, err = s.Cache.Get(200, 10)
+	require.NoError(t, err)
+
+	// Пользователь не получает его ни из кэша, ни из базы
+	_, _, err = s.GetBanner(200, 10, false, true)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	_, _, err = s.GetBanner(100, 10, true, true)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	assert.Equal(t, int64(2), s.bannerRepository.getBannerCalls.Load())
+}
+
+func TestBannerService_GetBannerActiveWindow(t *testing.T) {
+	now := time.Now()
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100},
+			ActiveWindow: models.ActiveWindow{ActiveFrom: n.NullInt64From(now.Add(time.Minute).Unix())}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 20, TagIds: []int64{100},
+			ActiveWindow: models.ActiveWindow{ActiveUntil: n.NullInt64From(now.Add(-time.Minute).Unix())}},
+		models.Banner{ID: 3, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 30, TagIds: []int64{100},
+			ActiveWindow: models.ActiveWindow{ActiveUntil: n.NullInt64From(now.Add(30 * time.Second).Unix())}},
+	)
+
+	t.Run("outside window is hidden from users", func(t *testing.T) {
+		for _, featureID := range []int64{10, 20} {
+			_, _, err := s.GetBanner(100, featureID, false, true)
+			assert.ErrorIs(t, err, errs.ErrNotFound)
+			_, _, err = s.GetBanner(100, featureID, true, true)
+			assert.ErrorIs(t, err, errs.ErrNotFound)
+
+			banner, _, err := s.GetBanner(100, featureID, false, false)
+			require.NoError(t, err)
+			assert.Equal(t, featureID, banner.FeatureID)
+		}
+	})
+
+	t.Run("inside window is shown to users", func(t *testing.T) {
+		banner, _, err := s.GetBanner(100, 30, false, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), banner.ID)
+	})
+
+	t.Run("max age is clipped to window boundary", func(t *testing.T) {
+		banner, _, err := s.GetBanner(100, 30, false, true)
+		require.NoError(t, err)
+
+		maxAge := s.CacheMaxAge(banner)
+		assert.LessOrEqual(t, maxAge, 30*time.Second)
+		assert.Greater(t, maxAge, time.Duration(0))
+
+		assert.Equal(t, s.CacheTTL, s.CacheMaxAge(models.Banner{}))
+	})
+}
+
+func TestBannerService_Variants(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100},
+			Variants: []models.BannerVariant{{Name: "a", Content: json.RawMessage(`{"title":"a"}`), Weight: 1}}},
+	)
+
+	// Варианты хранятся вместе с баннером и отдаются из кэша
+	_, _, err := s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	banner, _, err := s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	require.Len(t, banner.Variants, 1)
+	assert.Equal(t, "a", banner.Variants[0].Name)
+
+	t.Run("update without variants keeps them", func(t *testing.T) {
+		_, err := s.UpdateBanner(1, n.NullInt64{}, models.BannerPatch{Content: json.RawMessage(`{"title":"base"}`)})
+		require.NoError(t, err)
+
+		banner, _, err := s.GetBanner(100, 10, false, true)
+		require.NoError(t, err)
+		assert.Len(t, banner.Variants, 1)
+	})
+
+	t.Run("empty variants remove them", func(t *testing.T) {
+		_, err := s.UpdateBanner(1, n.NullInt64{}, models.BannerPatch{Variants: []models.BannerVariant{}})
+		require.NoError(t, err)
+
+		banner, _, err := s.GetBanner(100, 10, false, true)
+		require.NoError(t, err)
+		assert.Empty(t, banner.Variants)
+	})
+}
+
+func TestBannerService_NegativeCache(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+	)
+
+	t.Run("missing pair is cached", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, _, err := s.GetBanner(500, 50, false, true)
+			assert.ErrorIs(t, err, errs.ErrNotFound)
+		}
+		assert.Equal(t, int64(1), s.bannerRepository.getBannerCalls.Load())
+	})
+
+	t.Run("create evicts missing pair", func(t *testing.T) {
+		_, err := s.CreateBanner(models.BannerInput{TagIDs: []int64{500}, FeatureID: 50, Content: json.RawMessage(`{"title":"new"}`), IsActive: true})
+		require.NoError(t, err)
+
+		banner, _, err := s.GetBanner(500, 50, false, true)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"title":"new"}`, string(banner.Content))
+	})
+
+	t.Run("remap evicts missing pair", func(t *testing.T) {
+		_, _, err := s.GetBanner(600, 10, false, true)
+		require.ErrorIs(t, err, errs.ErrNotFound)
+		_, _, err = s.GetBanner(600, 10, false, true)
+		require.ErrorIs(t, err, ErrCachedNotFound)
+
+		_, err = s.UpdateBanner(1, n.NullInt64{}, models.BannerPatch{TagIDs: []int64{600}})
+		require.NoError(t, err)
+
+		banner, _, err := s.GetBanner(600, 10, false, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), banner.ID)
+	})
+}
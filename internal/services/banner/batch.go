@@ -0,0 +1,160 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Пакетная выдача баннеров по нескольким парам тег-фича. Пары ищутся так же, как в GetBanner:
+// в снимке, затем в кэше одним запросом, а промахи — одним запросом к базе.
+// Результаты идут в порядке пар, выключенный баннер или баннер вне окна показа
+// для пользователя отдаётся со статусом inactive. Если база недоступна, пары,
+// которых нет в последнем сохранённом состоянии, отдаются со статусом unavailable
+func (s *BannerService) GetBanners(keys []models.BannerKey, onlyActive bool) ([]models.BannerLookup, bool, error) {
+	unique := uniqueKeys(keys)
+	resolved := make(map[models.BannerKey]localEntry, len(unique))
+
+	pending := unique
+	if s.Snapshot != nil {
+		pending = make([]models.BannerKey, 0, len(unique))
+		for _, key := range unique {
+			banner, found, ok := s.Snapshot.Get(key.TagID, key.FeatureID)
+			if !ok {
+				pending = append(pending, key)
+				continue
+			}
+			resolved[key] = localEntry{banner: banner, notFound: !found}
+		}
+	}
+
+	var misses []models.BannerKey
+	if len(pending) > 0 {
+		// При ошибке кэша недостающие пары просто читаются из базы
+		entries, _ := s.Cache.GetMany(pending)
+		for _, key := range pending {
+			entry, ok := entries[key]
+			if !ok {
+				misses = append(misses, key)
+				continue
+			}
+			resolved[key] = entry
+		}
+	}
+
+	var degraded bool
+	var unavailable map[models.BannerKey]bool
+	if len(misses) > 0 {
+		var err error
+		unavailable, degraded, err = s.resolveMisses(misses, resolved)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	now := time.Now()
+	lookups := make([]models.BannerLookup, 0, len(keys))
+	for _, key := range keys {
+		entry := resolved[key]
+		lookup := models.BannerLookup{Key: key}
+		switch {
+		case unavailable[key]:
+			lookup.Status = models.BannerUnavailable
+			lookups = append(lookups, lookup)
+			continue
+		case entry.notFound:
+			lookup.Status = models.BannerNotFound
+		case onlyActive && !entry.banner.VisibleAt(now):
+			lookup.Status = models.BannerInactive
+		default:
+			lookup.Status = models.BannerFound
+			lookup.Banner = entry.banner
+		}
+		if !entry.notFound && !degraded {
+			s.recordLastKnownGood(entry.banner)
+		}
+		lookups = append(lookups, lookup)
+	}
+
+	return lookups, degraded, nil
+}
+
+// Чтение промахов кэша из базы и заполнение кэша в фоне. Если база недоступна,
+// пары берутся из последнего сохранённого состояния, и ответ помечается как degraded.
+// Пары, которых там нет, возвращаются отдельно, чтобы не ронять из-за них всю пачку
+func (s *BannerService) resolveMisses(misses []models.BannerKey, resolved map[models.BannerKey]localEntry) (map[models.BannerKey]bool, bool, error) {
+	var loaded []models.Banner
+	err := s.dbBreaker.Do(func() error {
+		var err error
+		loaded, err = s.BannerRepository.GetBanners(context.TODO(), misses)
+		return err
+	})
+	if err != nil {
+		if s.LastKnownGood == nil {
+			return nil, false, err
+		}
+		unavailable := make(map[models.BannerKey]bool)
+		for _, key := range misses {
+			banner, ok := s.LastKnownGood.Get(key.TagID, key.FeatureID)
+			if !ok {
+				unavailable[key] = true
+				continue
+			}
+			resolved[key] = localEntry{banner: banner}
+		}
+		s.logger.Warn("serving last known good banners",
+			slog.Int("count", len(misses)-len(unavailable)), slog.Int("unavailable", len(unavailable)), slog.String("error", err.Error()),
+		)
+		return unavailable, true, nil
+	}
+
+	byKey := make(map[models.BannerKey]models.Banner, len(loaded))
+	for _, banner := range loaded {
+		for _, tagID := range banner.TagIds {
+			byKey[models.BannerKey{TagID: tagID, FeatureID: banner.FeatureID}] = banner
+		}
+	}
+
+	filled := make(map[models.BannerKey]localEntry, len(misses))
+	for _, key := range misses {
+		entry := localEntry{notFound: true}
+		if banner, ok := byKey[key]; ok {
+			entry = localEntry{banner: banner}
+		}
+		resolved[key] = entry
+		filled[key] = entry
+	}
+
+	go s.fillCacheMany(filled)
+	return nil, false, nil
+}
+
+func (s *BannerService) fillCacheMany(entries map[models.BannerKey]localEntry) {
+	pushed := make(map[int64]bool, len(entries))
+	for key, entry := range entries {
+		if entry.notFound {
+			s.fillCache(key.TagID, key.FeatureID, models.Banner{}, errs.ErrNotFound)
+			continue
+		}
+		// Баннер кладётся под все свои пары за один раз
+		if pushed[entry.banner.ID] {
+			continue
+		}
+		pushed[entry.banner.ID] = true
+		s.fillCache(key.TagID, key.FeatureID, entry.banner, nil)
+	}
+}
+
+func uniqueKeys(keys []models.BannerKey) []models.BannerKey {
+	seen := make(map[models.BannerKey]bool, len(keys))
+	unique := make([]models.BannerKey, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
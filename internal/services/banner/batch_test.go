@@ -0,0 +1,100 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBannerService_GetBanners(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{"title":"one"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100, 101}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{"title":"two"}`), IsActive: false, FeatureID: 20, TagIds: []int64{100}},
+	)
+
+	keys := []models.BannerKey{
+		{TagID: 100, FeatureID: 10},
+		{TagID: 100, FeatureID: 20},
+		{TagID: 100, FeatureID: 30},
+		{TagID: 100, FeatureID: 10},
+	}
+
+	t.Run("user", func(t *testing.T) {
+		lookups, degraded, err := s.GetBanners(keys, true)
+		require.NoError(t, err)
+		assert.False(t, degraded)
+		require.Len(t, lookups, 4)
+
+		assert.Equal(t, models.BannerFound, lookups[0].Status)
+		assert.JSONEq(t, `{"title":"one"}`, string(lookups[0].Banner.Content))
+		assert.Equal(t, models.BannerInactive, lookups[1].Status)
+		assert.Nil(t, lookups[1].Banner.Content)
+		assert.Equal(t, models.BannerNotFound, lookups[2].Status)
+		assert.Equal(t, lookups[0], lookups[3])
+
+		assert.Equal(t, int64(1), s.bannerRepository.getBannersCalls.Load())
+
+		// Кэш заполняется в фоне
+		require.Eventually(t, func() bool {
+			values, _ := s.cache.GetMany([]string{cacheKey(100, 10), cacheKey(100, 20), cacheKey(100, 30)})
+			return len(values) == 3
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("admin sees inactive", func(t *testing.T) {
+		lookups, _, err := s.GetBanners(keys[1:2], false)
+		require.NoError(t, err)
+		assert.Equal(t, models.BannerFound, lookups[0].Status)
+		assert.Equal(t, int64(2), lookups[0].Banner.ID)
+	})
+
+	t.Run("cached after first request", func(t *testing.T) {
+		lookups, _, err := s.GetBanners(append(keys, models.BannerKey{TagID: 101, FeatureID: 10}), true)
+		require.NoError(t, err)
+		assert.Equal(t, models.BannerFound, lookups[4].Status)
+		assert.Equal(t, models.BannerNotFound, lookups[2].Status)
+
+		assert.Equal(t, int64(1), s.bannerRepository.getBannersCalls.Load())
+		assert.Equal(t, int64(0), s.bannerRepository.getBannerCalls.Load())
+	})
+}
+
+func TestBannerService_GetBannersLastKnownGood(t *testing.T) {
+	s := newTestServiceWithConfig(t, func(cfg *config.BannerService) {
+		cfg.LocalCacheSize = 0
+		cfg.LastKnownGoodPath = filepath.Join(t.TempDir(), "lkg.json")
+		cfg.LastKnownGoodFlushInterval = time.Minute
+	},
+		models.Banner{ID: 1, Content: json.RawMessage(`{"title":"one"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{"title":"two"}`), IsActive: true, FeatureID: 20, TagIds: []int64{100}},
+	)
+
+	known := models.BannerKey{TagID: 100, FeatureID: 10}
+	unknown := models.BannerKey{TagID: 100, FeatureID: 20}
+
+	_, _, err := s.GetBanners([]models.BannerKey{known}, true)
+	require.NoError(t, err)
+
+	// База и кэш больше не отвечают
+	s.bannerRepository.mu.Lock()
+	s.bannerRepository.getErr = errors.New("connection refused")
+	s.bannerRepository.mu.Unlock()
+	require.NoError(t, s.cache.Remove(cacheKey(100, 10)))
+
+	// Пара без сохранённого состояния не роняет остальные
+	lookups, degraded, err := s.GetBanners([]models.BannerKey{known, unknown}, true)
+	require.NoError(t, err)
+	assert.True(t, degraded)
+	require.Len(t, lookups, 2)
+	assert.Equal(t, models.BannerFound, lookups[0].Status)
+	assert.Equal(t, int64(1), lookups[0].Banner.ID)
+	assert.Equal(t, models.BannerUnavailable, lookups[1].Status)
+	assert.Nil(t, lookups[1].Banner.Content)
+}
@@ -1,21 +1,47 @@
 package banner
 
 import (
+	"backend-trainee-assignment-2024/internal/errs"
 	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/storage/memory"
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 )
 
+// Значение, которым в общем кэше помечается пара тег-фича без баннера.
+// Закодированный gob баннер никогда не бывает пустым, поэтому спутать их нельзя
+const notFoundValue = ""
+
+// Попадание в запись об отсутствии баннера, оборачивает errs.ErrNotFound
+var ErrCachedNotFound = fmt.Errorf("banner is cached as missing: %w", errs.ErrNotFound)
+
+type localEntry struct {
+	banner   models.Banner
+	notFound bool
+}
+
+// Двухуровневый кэш баннеров: LRU в памяти процесса перед общим кэшем (Redis).
+// Горячие пары тег-фича отдаются из памяти без сетевого запроса и декодирования
 type BannerCache struct {
-	cache Cache
+	cache    Cache
+	local    *memory.LRU[string, localEntry]
+	localTTL time.Duration
 }
 
-func NewBannerCache(cache Cache) *BannerCache {
-	return &BannerCache{
-		cache: cache,
+// Локальный уровень отключается при localSize = 0
+func NewBannerCache(cache Cache, localSize int, localTTL time.Duration) *BannerCache {
+	bc := &BannerCache{
+		cache:    cache,
+		localTTL: localTTL,
 	}
+	if localSize > 0 {
+		bc.local = memory.NewLRU[string, localEntry](localSize, localTTL)
+	}
+	return bc
 }
 
 func (bc *BannerCache) Push(banner models.Banner, ttl time.Duration) error {
@@ -25,23 +51,169 @@ func (bc *BannerCache) Push(banner models.Banner, ttl time.Duration) error {
 	}
 
 	for _, tagID := range banner.TagIds {
-		key := fmt.Sprintf("%d_%d", tagID, banner.FeatureID)
+		key := cacheKey(tagID, banner.FeatureID)
 		err := bc.cache.Push(key, string(data), ttl)
 		if err != nil {
 			return err
 		}
+		bc.setLocal(key, localEntry{banner: banner}, bc.localTTL)
 	}
 	return nil
 }
 
+// Запоминание того, что для пары тег-фича баннера нет.
+// Запись лежит под тем же ключом, поэтому сбрасывается теми же путями инвалидации
+func (bc *BannerCache) PushNotFound(tagID, featureID int64, ttl time.Duration) error {
+	key := cacheKey(tagID, featureID)
+	err := bc.cache.Push(key, notFoundValue, ttl)
+	if err != nil {
+		return err
+	}
+
+	localTTL := bc.localTTL
+	if ttl < localTTL {
+		localTTL = ttl
+	}
+	bc.setLocal(key, localEntry{notFound: true}, localTTL)
+	return nil
+}
+
+// При попадании в запись об отсутствии баннера возвращает ErrCachedNotFound,
+// при промахе — ошибку нижележащего кэша
 func (bc *BannerCache) Get(tagID, featureID int64) (models.Banner, error) {
-	key := fmt.Sprintf("%d_%d", tagID, featureID)
+	key := cacheKey(tagID, featureID)
+	if bc.local != nil {
+		if entry, ok := bc.local.Get(key); ok {
+			if entry.notFound {
+				return models.Banner{}, ErrCachedNotFound
+			}
+			return entry.banner, nil
+		}
+	}
+
 	data, err := bc.cache.Get(key)
 	if err != nil {
 		return models.Banner{}, err
 	}
-	fmt.Println("Cache works")
-	return decodeBanner([]byte(data))
+
+	if data == notFoundValue {
+		bc.setLocal(key, localEntry{notFound: true}, bc.localTTL)
+		return models.Banner{}, ErrCachedNotFound
+	}
+
+	banner, err := decodeBanner([]byte(data))
+	if err != nil {
+		return models.Banner{}, err
+	}
+
+	bc.setLocal(key, localEntry{banner: banner}, bc.localTTL)
+	return banner, nil
+}
+
+// Поиск сразу по нескольким парам: сначала в локальном уровне, остальные одним запросом к общему кэшу.
+// Пар, которых нет в кэше, в результате нет. При ошибке общего кэша возвращаются найденные в памяти
+func (bc *BannerCache) GetMany(keys []models.BannerKey) (map[models.BannerKey]localEntry, error) {
+	entries := make(map[models.BannerKey]localEntry, len(keys))
+	remoteKeys := make([]string, 0, len(keys))
+	byCacheKey := make(map[string]models.BannerKey, len(keys))
+
+	for _, key := range keys {
+		cacheKey := cacheKey(key.TagID, key.FeatureID)
+		if bc.local != nil {
+			if entry, ok := bc.local.Get(cacheKey); ok {
+				entries[key] = entry
+				continue
+			}
+		}
+		remoteKeys = append(remoteKeys, cacheKey)
+		byCacheKey[cacheKey] = key
+	}
+	if len(remoteKeys) == 0 {
+		return entries, nil
+	}
+
+	values, err := bc.cache.GetMany(remoteKeys)
+	if err != nil {
+		return entries, err
+	}
+
+	for cacheKey, data := range values {
+		entry := localEntry{notFound: true}
+		if data != notFoundValue {
+			banner, err := decodeBanner([]byte(data))
+			if err != nil {
+				// Битая запись считается промахом и перезапишется после чтения из базы
+				continue
+			}
+			entry = localEntry{banner: banner}
+		}
+
+		bc.setLocal(cacheKey, entry, bc.localTTL)
+		entries[byCacheKey[cacheKey]] = entry
+	}
+	return entries, nil
+}
+
+// Счётчики хранятся только в общем кэше, чтобы все инстансы видели одно значение
+func (bc *BannerCache) Incr(key string, ttl time.Duration) (int64, error) {
+	return bc.cache.Incr(key, ttl)
+}
+
+// Текущее значение счётчика без увеличения. Отсутствующий счётчик равен нулю
+func (bc *BannerCache) Counter(key string) (int64, error) {
+	value, err := bc.cache.Get(key)
+	if errors.Is(err, errs.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Счётчики попаданий в локальный уровень
+func (bc *BannerCache) LocalStats() memory.LRUStats {
+	if bc.local == nil {
+		return memory.LRUStats{}
+	}
+	return bc.local.Stats()
+}
+
+func (bc *BannerCache) setLocal(key string, entry localEntry, ttl time.Duration) {
+	if bc.local != nil {
+		bc.local.SetWithTTL(key, entry, ttl)
+	}
+}
+
+// Удаление из кэша всех ключей тегов и фичи баннера
+func (bc *BannerCache) Remove(banner models.Banner) error {
+	return bc.removeKeys(banner.TagIds, []int64{banner.FeatureID})
+}
+
+// Удаление из кэша ключей всех пар тег-фича, затронутых изменением баннера
+func (bc *BannerCache) RemoveChange(change models.BannerChange) error {
+	return bc.removeKeys(change.TagIDs, change.FeatureIDs)
+}
+
+func (bc *BannerCache) removeKeys(tagIDs, featureIDs []int64) error {
+	var errList []error
+	for _, featureID := range featureIDs {
+		for _, tagID := range tagIDs {
+			key := cacheKey(tagID, featureID)
+			if bc.local != nil {
+				bc.local.Remove(key)
+			}
+			err := bc.cache.Remove(key)
+			if err != nil {
+				errList = append(errList, err)
+			}
+		}
+	}
+	return errors.Join(errList...)
+}
+
+func cacheKey(tagID, featureID int64) string {
+	return fmt.Sprintf("%d_%d", tagID, featureID)
 }
 
 func encodeBanner(banner models.Banner) ([]byte, error) {
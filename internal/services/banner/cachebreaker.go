@@ -0,0 +1,188 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/breaker"
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ключ, которым фоновая проверка опрашивает кэш. Отсутствие значения считается успехом
+const probeKey = "cache_breaker_probe"
+
+type CacheBreakerStats struct {
+	State    string `json:"state"`
+	Opens    int64  `json:"opens"`
+	Rejected int64  `json:"rejected"`
+	Pending  int    `json:"pending_removals"`
+}
+
+// Circuit breaker перед общим кэшем. Пока он открыт, запросы сразу уходят в базу,
+// не дожидаясь таймаута кэша, а кэш опрашивается в фоне раз в probeInterval.
+// Удаления ключей, пришедшиеся на это время, откладываются и выполняются
+// перед закрытием breaker, чтобы после восстановления не отдать устаревшие записи
+type CacheBreaker struct {
+	cache    Cache
+	breaker  *breaker.Breaker
+	opens    atomic.Int64
+	rejected atomic.Int64
+	mu       sync.Mutex
+	pending  map[string]struct{}
+	stopCh   chan struct{}
+	logger   logger.Logger
+}
+
+func NewCacheBreaker(cache Cache, cfg config.Cache, logger logger.Logger) *CacheBreaker {
+	cb := &CacheBreaker{
+		cache:   cache,
+		breaker: breaker.New(cfg.BreakerMaxFailures, cfg.BreakerProbeInterval),
+		pending: make(map[string]struct{}),
+		stopCh:  make(chan struct{}),
+		logger:  logger,
+	}
+	// Промах кэша не отказ
+	cb.breaker.IsFailure = func(err error) bool {
+		return !errors.Is(err, errs.ErrNotFound)
+	}
+	cb.breaker.OnStateChange = func(from, to breaker.State) {
+		if to == breaker.Open {
+			cb.opens.Add(1)
+		}
+		logger.Warn("cache circuit breaker state changed", slog.String("from", from.String()), slog.String("to", to.String()))
+	}
+
+	go cb.runProbe(cfg.BreakerProbeInterval)
+	return cb
+}
+
+func (cb *CacheBreaker) Push(key, value string, ttl time.Duration) error {
+	return cb.call(func() error {
+		return cb.cache.Push(key, value, ttl)
+	})
+}
+
+func (cb *CacheBreaker) Get(key string) (string, error) {
+	var value string
+	err := cb.call(func() error {
+		var err error
+		value, err = cb.cache.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (cb *CacheBreaker) GetMany(keys []string) (map[string]string, error) {
+	var values map[string]string
+	err := cb.call(func() error {
+		var err error
+		values, err = cb.cache.GetMany(keys)
+		return err
+	})
+	return values, err
+}
+
+func (cb *CacheBreaker) Incr(key string, ttl time.Duration) (int64, error) {
+	var value int64
+	err := cb.call(func() error {
+		var err error
+		value, err = cb.cache.Incr(key, ttl)
+		return err
+	})
+	return value, err
+}
+
+// Пока breaker не закрыт, удаление откладывается до восстановления кэша и ошибкой не считается
+func (cb *CacheBreaker) Remove(key string) error {
+	err := cb.call(func() error {
+		return cb.cache.Remove(key)
+	})
+	if errors.Is(err, breaker.ErrOpen) {
+		cb.mu.Lock()
+		cb.pending[key] = struct{}{}
+		cb.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+func (cb *CacheBreaker) Stats() CacheBreakerStats {
+	cb.mu.Lock()
+	pending := len(cb.pending)
+	cb.mu.Unlock()
+
+	return CacheBreakerStats{
+		State:    cb.breaker.State().String(),
+		Opens:    cb.opens.Load(),
+		Rejected: cb.rejected.Load(),
+		Pending:  pending,
+	}
+}
+
+func (cb *CacheBreaker) Stop() {
+	close(cb.stopCh)
+}
+
+// Запросы проходят только через закрытый breaker: пробные вызовы делает фоновая проверка,
+// чтобы задержка недоступного кэша не попадала в запросы пользователей
+func (cb *CacheBreaker) call(fn func() error) error {
+	if cb.breaker.State() != breaker.Closed {
+		cb.rejected.Add(1)
+		return breaker.ErrOpen
+	}
+	return cb.breaker.Do(fn)
+}
+
+func (cb *CacheBreaker) runProbe(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cb.stopCh:
+			return
+		case <-ticker.C:
+			if cb.breaker.State() == breaker.Closed {
+				// Удаления, отложенные в момент закрытия breaker
+				cb.removePending()
+				continue
+			}
+
+			err := cb.breaker.Probe(cb.probe)
+			if err != nil {
+				cb.logger.Debug("cache is still unavailable", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (cb *CacheBreaker) probe() error {
+	_, err := cb.cache.Get(probeKey)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return err
+	}
+	return cb.removePending()
+}
+
+func (cb *CacheBreaker) removePending() error {
+	cb.mu.Lock()
+	keys := make([]string, 0, len(cb.pending))
+	for key := range cb.pending {
+		keys = append(keys, key)
+	}
+	cb.mu.Unlock()
+
+	for _, key := range keys {
+		if err := cb.cache.Remove(key); err != nil {
+			return err
+		}
+		cb.mu.Lock()
+		delete(cb.pending, key)
+		cb.mu.Unlock()
+	}
+	return nil
+}
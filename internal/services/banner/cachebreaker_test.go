@@ -0,0 +1,125 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/breaker"
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/storage/memory"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Кэш, который по флагу перестаёт отвечать
+type flakyCache struct {
+	*memory.Cache
+	down  atomic.Bool
+	calls atomic.Int64
+}
+
+var errCacheDown = errors.New("i/o timeout")
+
+func (c *flakyCache) Push(key, value string, ttl time.Duration) error {
+	c.calls.Add(1)
+	if c.down.Load() {
+		return errCacheDown
+	}
+	return c.Cache.Push(key, value, ttl)
+}
+
+func (c *flakyCache) Get(key string) (string, error) {
+	c.calls.Add(1)
+	if c.down.Load() {
+		return "", errCacheDown
+	}
+	return c.Cache.Get(key)
+}
+
+func (c *flakyCache) GetMany(keys []string) (map[string]string, error) {
+	c.calls.Add(1)
+	if c.down.Load() {
+		return nil, errCacheDown
+	}
+	return c.Cache.GetMany(keys)
+}
+
+func (c *flakyCache) Remove(key string) error {
+	c.calls.Add(1)
+	if c.down.Load() {
+		return errCacheDown
+	}
+	return c.Cache.Remove(key)
+}
+
+func (c *flakyCache) Incr(key string, ttl time.Duration) (int64, error) {
+	c.calls.Add(1)
+	if c.down.Load() {
+		return 0, errCacheDown
+	}
+	return c.Cache.Incr(key, ttl)
+}
+
+func TestCacheBreaker(t *testing.T) {
+	cache := &flakyCache{Cache: memory.NewCache(time.Minute)}
+	t.Cleanup(func() { cache.Close() })
+
+	cb := NewCacheBreaker(cache, config.Cache{
+		BreakerMaxFailures:   2,
+		BreakerProbeInterval: 10 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(cb.Stop)
+
+	require.NoError(t, cb.Push("1_1", "banner", time.Minute))
+
+	t.Run("miss is not a failure", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, err := cb.Get("2_2")
+			assert.ErrorIs(t, err, errs.ErrNotFound)
+		}
+		assert.Equal(t, "closed", cb.Stats().State)
+	})
+
+	t.Run("opens after failures", func(t *testing.T) {
+		cache.down.Store(true)
+		for i := 0; i < 2; i++ {
+			_, err := cb.Get("1_1")
+			assert.ErrorIs(t, err, errCacheDown)
+		}
+
+		calls := cache.calls.Load()
+		_, err := cb.Get("1_1")
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+		assert.ErrorIs(t, cb.Push("1_1", "banner", time.Minute), breaker.ErrOpen)
+
+		// Удаление откладывается до восстановления
+		assert.NoError(t, cb.Remove("1_1"))
+
+		stats := cb.Stats()
+		assert.Equal(t, "open", stats.State)
+		assert.Equal(t, int64(1), stats.Opens)
+		assert.Equal(t, int64(3), stats.Rejected)
+		assert.Equal(t, 1, stats.Pending)
+
+		// Запросы к кэшу делает только фоновая проверка
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, "open", cb.Stats().State)
+		assert.Greater(t, cache.calls.Load(), calls)
+	})
+
+	t.Run("closes after probe and applies pending removals", func(t *testing.T) {
+		cache.down.Store(false)
+		require.Eventually(t, func() bool {
+			return cb.Stats().State == "closed"
+		}, time.Second, 5*time.Millisecond)
+
+		assert.Equal(t, 0, cb.Stats().Pending)
+		_, err := cb.Get("1_1")
+		assert.ErrorIs(t, err, errs.ErrNotFound)
+	})
+}
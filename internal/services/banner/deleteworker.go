@@ -1,30 +1,63 @@
 package banner
 
 import (
+	"backend-trainee-assignment-2024/internal/errs"
 	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
 	"context"
-	"fmt"
-	"time"
+	"encoding/json"
+	"errors"
+	"log/slog"
 )
 
 type DeleteBannerWorker struct {
-	bannerRepository BannerRepository
-	queue            *BannerQueue
-	stopCh           chan struct{}
-	logger           logger.Logger
+	bannerRepository     BannerRepository
+	jobRepository        JobRepository
+	deadLetterRepository DeadLetterRepository
+	cache                *BannerCache
+	queue                *BannerQueue
+	batchSize            uint64
+	retryPolicy          RetryPolicy
+	stopCh               chan struct{}
+	logger               logger.Logger
 }
 
-func NewDeleteBannerWorker(bannerRepository BannerRepository, queue *BannerQueue, logger logger.Logger) (*DeleteBannerWorker, chan struct{}) {
+func NewDeleteBannerWorker(
+	bannerRepository BannerRepository,
+	jobRepository JobRepository,
+	deadLetterRepository DeadLetterRepository,
+	cache *BannerCache,
+	queue *BannerQueue,
+	batchSize int,
+	retryPolicy RetryPolicy,
+	logger logger.Logger,
+) (*DeleteBannerWorker, chan struct{}) {
 	stopCh := make(chan struct{})
 	return &DeleteBannerWorker{
-		bannerRepository: bannerRepository,
-		queue:            queue,
-		stopCh:           stopCh,
-		logger:           logger,
+		bannerRepository:     bannerRepository,
+		jobRepository:        jobRepository,
+		deadLetterRepository: deadLetterRepository,
+		cache:                cache,
+		queue:                queue,
+		batchSize:            uint64(batchSize),
+		retryPolicy:          retryPolicy,
+		stopCh:               stopCh,
+		logger:               logger,
 	}, stopCh
 }
 
 func (w *DeleteBannerWorker) Run(ctx context.Context, stopCh chan struct{}) error {
+	// Отмена ожидания сообщения из очереди при остановке воркера
+	consumeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-consumeCtx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -32,42 +65,154 @@ func (w *DeleteBannerWorker) Run(ctx context.Context, stopCh chan struct{}) erro
 		case <-stopCh:
 			return nil
 		default:
-			task, err := w.queue.Consume()
-			if err != nil {
-				w.logger.Error("error consuming from queue: %v", err)
-				continue
-			}
-
-			err = w.deleteBanner(ctx, task)
+			delivery, err := w.queue.Consume(consumeCtx)
 			if err != nil {
-				w.logger.Error("error deleting banner: %v", err)
-				if err := w.retryDeleteBanner(ctx, task); err != nil {
-					w.logger.Error("error retrying delete banner: %v", err)
+				if consumeCtx.Err() != nil {
 					continue
 				}
+				if errors.Is(err, ErrQueueClosed) {
+					return err
+				}
+				w.logger.Error("error consuming from queue", slog.String("error", err.Error()))
+				continue
 			}
+
+			w.handleDelivery(ctx, delivery)
 		}
 	}
 }
 
-func (w *DeleteBannerWorker) deleteBanner(ctx context.Context, task *DeleteBannerTask) error {
-	return w.bannerRepository.DeleteBanner(ctx, task.ID)
+func (w *DeleteBannerWorker) handleDelivery(ctx context.Context, delivery *DeleteBannerDelivery) {
+	task := delivery.Task
+	w.setJobStatus(ctx, task.JobID, models.JobRunning, "")
+
+	err := w.deleteBanner(ctx, task)
+	if err == nil {
+		w.setJobStatus(ctx, task.JobID, models.JobDone, "")
+		w.ack(delivery)
+		return
+	}
+
+	w.logger.Error("error deleting banner",
+		slog.Int64("job_id", task.JobID),
+		slog.Int("attempt", task.Attempt),
+		slog.String("error", err.Error()),
+	)
+
+	retry := *task
+	retry.Attempt++
+	if retry.Attempt >= w.retryPolicy.MaxAttempts {
+		w.deadLetter(ctx, delivery, &retry, err)
+		return
+	}
+
+	w.setJobStatus(ctx, task.JobID, models.JobQueued, err.Error())
+	w.scheduleRetry(ctx, delivery, &retry)
+}
+
+// Повтор публикуется сразу, а задержку выдерживает брокер, поэтому повтор не теряется при остановке
+// сервиса и не дублируется повторной доставкой. Исходное сообщение подтверждается только после
+// публикации повтора: при падении сервиса между ними задача будет доставлена заново
+func (w *DeleteBannerWorker) scheduleRetry(ctx context.Context, delivery *DeleteBannerDelivery, retry *DeleteBannerTask) {
+	err := w.queue.PublishDelayed(retry, w.retryPolicy.Backoff(retry.Attempt-1))
+	if err != nil {
+		w.logger.Error("error republishing delete task", slog.Int64("job_id", retry.JobID), slog.String("error", err.Error()))
+		w.deadLetter(ctx, delivery, retry, err)
+		return
+	}
+	w.ack(delivery)
 }
 
-func (w *DeleteBannerWorker) retryDeleteBanner(ctx context.Context, task *DeleteBannerTask) error {
-	var retries int
-	maxRetries := 5
-	for retries < maxRetries {
-		err := w.deleteBanner(ctx, task)
-		if err == nil {
+func (w *DeleteBannerWorker) deadLetter(ctx context.Context, delivery *DeleteBannerDelivery, task *DeleteBannerTask, cause error) {
+	w.setJobStatus(ctx, task.JobID, models.JobFailed, cause.Error())
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		w.logger.Error("error encoding dead letter", slog.Int64("job_id", task.JobID), slog.String("error", err.Error()))
+		w.ack(delivery)
+		return
+	}
+
+	_, err = w.deadLetterRepository.AddDeadLetter(ctx, task.JobID, payload, cause.Error(), task.Attempt)
+	if err != nil {
+		w.logger.Error("error saving dead letter", slog.Int64("job_id", task.JobID), slog.String("error", err.Error()))
+		// Задача вернётся в очередь не сразу, чтобы не крутить её в цикле, пока хранилище недоступно.
+		// Если и брокер не принял задачу, она возвращается в очередь немедленно
+		if err := w.queue.PublishDelayed(task, w.retryPolicy.MaxDelay); err != nil {
+			w.nack(delivery)
+			return
+		}
+		w.ack(delivery)
+		return
+	}
+
+	w.ack(delivery)
+}
+
+func (w *DeleteBannerWorker) ack(delivery *DeleteBannerDelivery) {
+	if err := delivery.Ack(); err != nil {
+		w.logger.Error("error acknowledging delete task", slog.Int64("job_id", delivery.Task.JobID), slog.String("error", err.Error()))
+	}
+}
+
+func (w *DeleteBannerWorker) nack(delivery *DeleteBannerDelivery) {
+	if err := delivery.Nack(true); err != nil {
+		w.logger.Error("error returning delete task to queue", slog.Int64("job_id", delivery.Task.JobID), slog.String("error", err.Error()))
+	}
+}
+
+func (w *DeleteBannerWorker) setJobStatus(ctx context.Context, jobID int64, status models.JobStatus, errMsg string) {
+	err := w.jobRepository.UpdateJobStatus(ctx, jobID, status, errMsg)
+	if err != nil {
+		w.logger.Error("error updating job status", slog.Int64("job_id", jobID), slog.String("error", err.Error()))
+	}
+}
+
+func (w *DeleteBannerWorker) deleteBanner(ctx context.Context, task *DeleteBannerTask) error {
+	if task.Filter.BannerID.Valid {
+		bannerID := task.Filter.BannerID.Int64
+
+		// Теги и фича баннера нужны для инвалидации кэша после удаления
+		banner, err := w.bannerRepository.GetBannerByID(ctx, bannerID)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+
+		err = w.bannerRepository.DeleteBanner(ctx, bannerID)
+		// Баннер уже удалён, например, повторно доставленной задачей
+		if errors.Is(err, errs.ErrNotFound) {
 			return nil
 		}
-		retries++
-		err = w.queue.Publish(task)
 		if err != nil {
 			return err
 		}
-		time.Sleep(time.Second * 5) // Ожидает 5 секунд перед следующей попыткой
+
+		w.invalidate(banner)
+		return nil
+	}
+	return w.deleteBanners(ctx, task.Filter)
+}
+
+// Удаление баннеров по фиче и/или тегу пачками, чтобы не держать долгую транзакцию
+func (w *DeleteBannerWorker) deleteBanners(ctx context.Context, filter DeleteBannerFilter) error {
+	for {
+		deleted, err := w.bannerRepository.DeleteBanners(ctx, filter.FeatureID, filter.TagID, w.batchSize)
+		if err != nil {
+			return err
+		}
+
+		w.invalidate(deleted...)
+		if uint64(len(deleted)) < w.batchSize {
+			return nil
+		}
+	}
+}
+
+func (w *DeleteBannerWorker) invalidate(banners ...models.Banner) {
+	for _, banner := range banners {
+		err := w.cache.Remove(banner)
+		if err != nil {
+			w.logger.Error("error invalidating banner cache", slog.Int64("banner_id", banner.ID), slog.String("error", err.Error()))
+		}
 	}
-	return fmt.Errorf("failed to delete banner after %d retries", maxRetries)
 }
@@ -0,0 +1,161 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type EventRepository interface {
+	InsertEvents(ctx context.Context, events []models.Event) error
+	GetBannerStats(ctx context.Context, bannerID int64, from time.Time) ([]models.DailyStats, error)
+}
+
+type EventBufferStats struct {
+	Pending int    `json:"pending"`
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// Буфер событий показов и кликов. События копятся в памяти и пишутся в базу пачками
+// раз в flushInterval или сразу, как только набралась пачка из batchSize событий.
+// Буфер ограничен maxSize: при переполнении (например, база долго недоступна) новые события
+// отбрасываются, чтобы учёт не мешал выдаче баннеров
+type EventBuffer struct {
+	repository EventRepository
+	batchSize  int
+	maxSize    int
+	flushCh    chan struct{}
+	mu         sync.Mutex
+	events     []models.Event
+	written    atomic.Uint64
+	dropped    atomic.Uint64
+	logger     logger.Logger
+}
+
+func NewEventBuffer(repository EventRepository, batchSize, maxSize int, logger logger.Logger) *EventBuffer {
+	return &EventBuffer{
+		repository: repository,
+		batchSize:  batchSize,
+		maxSize:    maxSize,
+		flushCh:    make(chan struct{}, 1),
+		events:     make([]models.Event, 0, batchSize),
+		logger:     logger,
+	}
+}
+
+// Добавление событий в буфер, возвращает число принятых
+func (b *EventBuffer) Add(events ...models.Event) int {
+	b.mu.Lock()
+	accepted := min(len(events), b.maxSize-len(b.events))
+	accepted = max(accepted, 0)
+	b.events = append(b.events, events[:accepted]...)
+	full := len(b.events) >= b.batchSize
+	b.mu.Unlock()
+
+	if dropped := len(events) - accepted; dropped > 0 {
+		b.dropped.Add(uint64(dropped))
+	}
+	if full {
+		select {
+		case b.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return accepted
+}
+
+// Запись накопленных событий до закрытия stopCh
+func (b *EventBuffer) Run(flushInterval time.Duration, stopCh chan struct{}) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		case <-b.flushCh:
+		}
+
+		err := b.Flush(context.Background())
+		if err != nil {
+			b.logger.Error("error writing banner events", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Запись всех накопленных событий пачками по batchSize. Если пачку записать не удалось,
+// она возвращается в буфер, насколько хватает места, и будет записана при следующей попытке
+func (b *EventBuffer) Flush(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		size := min(len(b.events), b.batchSize)
+		batch := b.events[:size:size]
+		b.events = b.events[size:]
+		b.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := b.repository.InsertEvents(ctx, batch)
+		if err != nil {
+			b.requeue(batch)
+			return err
+		}
+		b.written.Add(uint64(len(batch)))
+	}
+}
+
+func (b *EventBuffer) requeue(batch []models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	kept := min(len(batch), max(b.maxSize-len(b.events), 0))
+	if dropped := len(batch) - kept; dropped > 0 {
+		b.dropped.Add(uint64(dropped))
+	}
+	b.events = append(batch[:kept:kept], b.events...)
+}
+
+func (b *EventBuffer) Stats() EventBufferStats {
+	b.mu.Lock()
+	pending := len(b.events)
+	b.mu.Unlock()
+
+	return EventBufferStats{
+		Pending: pending,
+		Written: b.written.Load(),
+		Dropped: b.dropped.Load(),
+	}
+}
+
+// Приём событий от клиентов. Время события — момент приёма, а не записи в базу
+func (s *BannerService) TrackEvents(userID int64, events []models.Event) int {
+	now := time.Now()
+	for i := range events {
+		events[i].UserID = userID
+		events[i].CreatedAt = now
+	}
+	return s.Events.Add(events...)
+}
+
+// Статистика баннера по суткам за последние days дней, включая текущие
+func (s *BannerService) GetBannerStats(bannerID int64, days int) ([]models.DailyStats, error) {
+	_, err := s.BannerRepository.GetBannerByID(context.TODO(), bannerID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return s.EventRepository.GetBannerStats(context.TODO(), bannerID, today.AddDate(0, 0, 1-days))
+}
+
+func (s *BannerService) EventStats() EventBufferStats {
+	return s.Events.Stats()
+}
@@ -0,0 +1,90 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func impressions(count int) []models.Event {
+	events := make([]models.Event, count)
+	for i := range events {
+		events[i] = models.Event{Type: models.EventImpression, BannerID: 1, Variant: models.DefaultVariantName, TagID: 100}
+	}
+	return events
+}
+
+func TestEventBuffer_Flush(t *testing.T) {
+	repository := newFakeEventRepository()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	buffer := NewEventBuffer(repository, 2, 4, logger)
+
+	t.Run("overflow is dropped", func(t *testing.T) {
+		assert.Equal(t, 3, buffer.Add(impressions(3)...))
+		assert.Equal(t, 1, buffer.Add(impressions(2)...))
+		assert.Equal(t, EventBufferStats{Pending: 4, Dropped: 1}, buffer.Stats())
+	})
+
+	t.Run("failed batch is kept", func(t *testing.T) {
+		repository.setErr(errors.New("db is down"))
+		require.Error(t, buffer.Flush(context.Background()))
+		assert.Equal(t, EventBufferStats{Pending: 4, Dropped: 1}, buffer.Stats())
+	})
+
+	t.Run("events are written in batches", func(t *testing.T) {
+		repository.setErr(nil)
+		require.NoError(t, buffer.Flush(context.Background()))
+
+		events, batches := repository.written()
+		assert.Len(t, events, 4)
+		assert.Equal(t, 2, batches)
+		assert.Equal(t, EventBufferStats{Pending: 0, Written: 4, Dropped: 1}, buffer.Stats())
+	})
+}
+
+func TestBannerService_TrackEvents(t *testing.T) {
+	s := newTestServiceWithConfig(t, func(cfg *config.BannerService) {
+		cfg.EventsFlushInterval = 10 * time.Millisecond
+		cfg.EventsBatchSize = 2
+	},
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+	)
+
+	events := impressions(3)
+	events[2].Type = models.EventClick
+	assert.Equal(t, 3, s.TrackEvents(7, events))
+
+	require.Eventually(t, func() bool {
+		written, _ := s.eventRepository.written()
+		return len(written) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	written, _ := s.eventRepository.written()
+	assert.Equal(t, int64(7), written[0].UserID)
+	assert.False(t, written[0].CreatedAt.IsZero())
+
+	t.Run("daily stats", func(t *testing.T) {
+		stats, err := s.GetBannerStats(1, 7)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, time.Now().UTC().Format(time.DateOnly), stats[0].Day)
+		assert.Equal(t, int64(2), stats[0].Impressions)
+		assert.Equal(t, int64(1), stats[0].Clicks)
+		assert.InDelta(t, 0.5, stats[0].CTR, 1e-9)
+	})
+
+	t.Run("missing banner", func(t *testing.T) {
+		_, err := s.GetBannerStats(2, 7)
+		assert.ErrorIs(t, err, errs.ErrNotFound)
+	})
+}
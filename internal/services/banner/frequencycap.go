@@ -0,0 +1,42 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"log/slog"
+	"time"
+)
+
+// Учёт показа баннера пользователю. Возвращает false, если пользователь уже исчерпал
+// ограничение частоты показов в текущем окне. Если кэш недоступен, показ разрешается:
+// ограничение частоты не должно ломать выдачу
+func (s *BannerService) AllowImpression(banner models.Banner, userID int64) bool {
+	if banner.FrequencyCap == nil {
+		return true
+	}
+
+	key, ttl := banner.FrequencyCap.CounterKey(banner.ID, userID, time.Now())
+	count, err := s.Cache.Incr(key, ttl)
+	if err != nil {
+		s.logger.Warn("error counting banner impression", slog.Int64("banner_id", banner.ID), slog.String("error", err.Error()))
+		return true
+	}
+
+	return count <= banner.FrequencyCap.Count
+}
+
+// Исчерпал ли пользователь ограничение частоты показов в текущем окне. Показ не учитывается:
+// так проверяется перепроверка по ETag, после которой клиент покажет копию из своего кэша
+func (s *BannerService) ImpressionCapped(banner models.Banner, userID int64) bool {
+	if banner.FrequencyCap == nil {
+		return false
+	}
+
+	key, _ := banner.FrequencyCap.CounterKey(banner.ID, userID, time.Now())
+	count, err := s.Cache.Counter(key)
+	if err != nil {
+		s.logger.Warn("error reading banner impressions", slog.Int64("banner_id", banner.ID), slog.String("error", err.Error()))
+		return false
+	}
+
+	return count >= banner.FrequencyCap.Count
+}
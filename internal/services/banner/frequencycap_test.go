@@ -0,0 +1,49 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBannerService_AllowImpression(t *testing.T) {
+	s := newTestService(t)
+	capped := models.Banner{ID: 1, Content: json.RawMessage(`{}`), FrequencyCap: &models.FrequencyCap{Count: 2, Window: 86400}}
+
+	t.Run("cap is per user", func(t *testing.T) {
+		assert.True(t, s.AllowImpression(capped, 7))
+		assert.True(t, s.AllowImpression(capped, 7))
+		assert.False(t, s.AllowImpression(capped, 7))
+		assert.False(t, s.AllowImpression(capped, 7))
+
+		assert.True(t, s.AllowImpression(capped, 8))
+	})
+
+	t.Run("capped check does not count", func(t *testing.T) {
+		assert.False(t, s.ImpressionCapped(capped, 9))
+		assert.True(t, s.AllowImpression(capped, 9))
+		assert.False(t, s.ImpressionCapped(capped, 9))
+		assert.True(t, s.AllowImpression(capped, 9))
+		assert.True(t, s.ImpressionCapped(capped, 9))
+	})
+
+	t.Run("banner without cap", func(t *testing.T) {
+		banner := models.Banner{ID: 2, Content: json.RawMessage(`{}`)}
+		for i := 0; i < 5; i++ {
+			assert.True(t, s.AllowImpression(banner, 7))
+		}
+	})
+
+	t.Run("counter resets in next window", func(t *testing.T) {
+		frequencyCap := models.FrequencyCap{Count: 1, Window: 60}
+		now := time.Unix(1712000010, 0)
+
+		key, ttl := frequencyCap.CounterKey(1, 7, now)
+		nextKey, _ := frequencyCap.CounterKey(1, 7, now.Add(30*time.Second))
+		assert.NotEqual(t, key, nextKey)
+		assert.Equal(t, 30*time.Second, ttl)
+	})
+}
@@ -0,0 +1,139 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Последнее успешно отданное состояние выдачи, сохраняемое в файл.
+// Используется как запасной источник, когда ни кэш, ни база не могут ответить,
+// и переживает перезапуск инстанса
+type LastKnownGood struct {
+	path    string
+	mu      sync.Mutex
+	banners map[string]models.Banner
+	dirty   bool
+}
+
+// Загружает ранее сохранённое состояние, если файл уже есть. Если файл прочитать не удалось,
+// возвращает пустое, но рабочее состояние вместе с ошибкой: файл перезапишется при следующем сохранении
+func NewLastKnownGood(path string) (*LastKnownGood, error) {
+	lkg := &LastKnownGood{
+		path:    path,
+		banners: make(map[string]models.Banner),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lkg, nil
+		}
+		return lkg, err
+	}
+
+	var banners []models.Banner
+	if err := json.Unmarshal(data, &banners); err != nil {
+		return lkg, err
+	}
+	for _, banner := range banners {
+		lkg.put(banner)
+	}
+
+	return lkg, nil
+}
+
+func (l *LastKnownGood) Record(banner models.Banner) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.put(banner)
+	l.dirty = true
+}
+
+func (l *LastKnownGood) Get(tagID, featureID int64) (models.Banner, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	banner, ok := l.banners[cacheKey(tagID, featureID)]
+	return banner, ok
+}
+
+// Удаление пар, затронутых изменением, чтобы при отказе не отдать удалённый или перенесённый баннер
+func (l *LastKnownGood) Remove(change models.BannerChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, featureID := range change.FeatureIDs {
+		for _, tagID := range change.TagIDs {
+			key := cacheKey(tagID, featureID)
+			if banner, ok := l.banners[key]; ok && banner.ID == change.BannerID {
+				delete(l.banners, key)
+				l.dirty = true
+			}
+		}
+	}
+}
+
+// Запись состояния в файл, если оно менялось. Файл подменяется атомарно через rename,
+// поэтому падение посреди записи не оставит его битым
+func (l *LastKnownGood) Flush() error {
+	l.mu.Lock()
+	if !l.dirty {
+		l.mu.Unlock()
+		return nil
+	}
+
+	seen := make(map[int64]bool, len(l.banners))
+	banners := make([]models.Banner, 0, len(l.banners))
+	for _, banner := range l.banners {
+		if !seen[banner.ID] {
+			seen[banner.ID] = true
+			banners = append(banners, banner)
+		}
+	}
+	l.dirty = false
+	l.mu.Unlock()
+
+	err := l.write(banners)
+	if err != nil {
+		// Состояние запишется при следующей попытке
+		l.mu.Lock()
+		l.dirty = true
+		l.mu.Unlock()
+	}
+	return err
+}
+
+func (l *LastKnownGood) write(banners []models.Banner) error {
+	data, err := json.Marshal(banners)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// Баннер хранится под каждой своей парой тег-фича
+func (l *LastKnownGood) put(banner models.Banner) {
+	for _, tagID := range banner.TagIds {
+		l.banners[cacheKey(tagID, banner.FeatureID)] = banner
+	}
+}
@@ -0,0 +1,104 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastKnownGood(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lkg.json")
+
+	lkg, err := NewLastKnownGood(path)
+	require.NoError(t, err)
+
+	lkg.Record(models.Banner{ID: 1, Content: json.RawMessage(`{"title":"one"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100, 101}})
+	lkg.Record(models.Banner{ID: 2, Content: json.RawMessage(`{"title":"two"}`), IsActive: true, FeatureID: 20, TagIds: []int64{200}})
+
+	t.Run("remove keeps pair of another banner", func(t *testing.T) {
+		lkg.Remove(models.BannerChange{BannerID: 3, FeatureIDs: []int64{10}, TagIDs: []int64{100}})
+		_, ok := lkg.Get(100, 10)
+		assert.True(t, ok)
+	})
+
+	t.Run("flush and load", func(t *testing.T) {
+		lkg.Remove(models.BannerChange{BannerID: 2, FeatureIDs: []int64{20}, TagIDs: []int64{200}})
+		require.NoError(t, lkg.Flush())
+
+		loaded, err := NewLastKnownGood(path)
+		require.NoError(t, err)
+
+		banner, ok := loaded.Get(101, 10)
+		require.True(t, ok)
+		assert.Equal(t, int64(1), banner.ID)
+		assert.JSONEq(t, `{"title":"one"}`, string(banner.Content))
+
+		_, ok = loaded.Get(200, 20)
+		assert.False(t, ok)
+	})
+
+	t.Run("broken file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+
+		loaded, err := NewLastKnownGood(path)
+		assert.Error(t, err)
+		require.NotNil(t, loaded)
+
+		loaded.Record(models.Banner{ID: 1, FeatureID: 10, TagIds: []int64{100}})
+		assert.NoError(t, loaded.Flush())
+	})
+}
+
+func TestBannerService_GetBannerLastKnownGood(t *testing.T) {
+	s := newTestServiceWithConfig(t, func(cfg *config.BannerService) {
+		cfg.LocalCacheSize = 0
+		cfg.LastKnownGoodPath = filepath.Join(t.TempDir(), "lkg.json")
+		cfg.LastKnownGoodFlushInterval = time.Minute
+		cfg.DBBreakerMaxFailures = 2
+		cfg.DBBreakerOpenTimeout = time.Minute
+	},
+		models.Banner{ID: 1, Content: json.RawMessage(`{"title":"one"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+	)
+
+	banner, degraded, err := s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	assert.False(t, degraded)
+
+	// База и кэш больше не отвечают
+	s.bannerRepository.mu.Lock()
+	s.bannerRepository.getErr = errors.New("connection refused")
+	s.bannerRepository.mu.Unlock()
+	require.NoError(t, s.cache.Remove(cacheKey(100, 10)))
+
+	banner, degraded, err = s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	assert.True(t, degraded)
+	assert.Equal(t, int64(1), banner.ID)
+
+	_, _, err = s.GetBanner(200, 10, false, true)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, errs.ErrNotFound))
+
+	// После двух отказов подряд breaker открыт, и база больше не вызывается
+	calls := s.bannerRepository.getBannerCalls.Load()
+	banner, degraded, err = s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	assert.True(t, degraded)
+	assert.Equal(t, int64(1), banner.ID)
+	assert.Equal(t, calls, s.bannerRepository.getBannerCalls.Load())
+
+	require.NoError(t, s.LastKnownGood.Flush())
+	loaded, err := NewLastKnownGood(s.LastKnownGood.path)
+	require.NoError(t, err)
+	_, ok := loaded.Get(100, 10)
+	assert.True(t, ok)
+}
@@ -0,0 +1,15 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+)
+
+// Выбор перевода баннера. После языков из запроса пробуются языки из конфигурации,
+// если не подошёл ни один, отдаётся основное содержимое с пустым языком
+func (s *BannerService) Localize(banner models.Banner, locales []string) (string, json.RawMessage) {
+	chain := make([]string, 0, len(locales)+len(s.LocaleFallback))
+	chain = append(chain, locales...)
+	chain = append(chain, s.LocaleFallback...)
+	return banner.Localize(chain)
+}
@@ -0,0 +1,62 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBannerService_Localize(t *testing.T) {
+	s := newTestServiceWithConfig(t, func(cfg *config.BannerService) {
+		cfg.LocaleFallback = []string{"en"}
+	})
+
+	banner := models.Banner{
+		Content: json.RawMessage(`{"title":"base"}`),
+		LocalizedContent: models.LocalizedContent{
+			"en": json.RawMessage(`{"title":"hello"}`),
+			"ru": json.RawMessage(`{"title":"привет"}`),
+		},
+	}
+
+	t.Run("requested locale", func(t *testing.T) {
+		locale, content := s.Localize(banner, []string{"ru-ru"})
+		assert.Equal(t, "ru", locale)
+		assert.JSONEq(t, `{"title":"привет"}`, string(content))
+	})
+
+	t.Run("fallback chain", func(t *testing.T) {
+		locale, content := s.Localize(banner, []string{"de"})
+		assert.Equal(t, "en", locale)
+		assert.JSONEq(t, `{"title":"hello"}`, string(content))
+	})
+
+	t.Run("base content without matching translation", func(t *testing.T) {
+		locale, content := s.Localize(models.Banner{Content: banner.Content, LocalizedContent: models.LocalizedContent{"ru": banner.LocalizedContent["ru"]}}, []string{"de"})
+		assert.Empty(t, locale)
+		assert.Equal(t, banner.Content, content)
+	})
+
+	t.Run("patch merges translations", func(t *testing.T) {
+		bannerID, err := s.CreateBanner(models.BannerInput{TagIDs: []int64{1}, FeatureID: 1, Content: json.RawMessage(`{"title":"base"}`), IsActive: true, LocalizedContent: banner.LocalizedContent})
+		require.NoError(t, err)
+
+		_, err = s.UpdateBanner(bannerID, n.NullInt64{}, models.BannerPatch{LocalizedContent: models.LocalizedContent{
+			"ru": json.RawMessage(`null`),
+			"de": json.RawMessage(`{"title":"hallo"}`),
+		}})
+		require.NoError(t, err)
+
+		updated, err := s.GetBannerByID(bannerID)
+		require.NoError(t, err)
+		assert.Equal(t, models.LocalizedContent{
+			"en": json.RawMessage(`{"title":"hello"}`),
+			"de": json.RawMessage(`{"title":"hallo"}`),
+		}, updated.LocalizedContent)
+	})
+}
@@ -1,17 +1,51 @@
 package banner
 
 import (
+	"backend-trainee-assignment-2024/internal/broker"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
 	"bytes"
 	"encoding/gob"
 )
 
+var ErrQueueClosed = errors.New("queue is closed")
+
+// Фильтр баннеров для удаления: либо конкретный баннер, либо все баннеры фичи и/или тега
+type DeleteBannerFilter struct {
+	BannerID  n.NullInt64 `json:"banner_id"`
+	FeatureID n.NullInt64 `json:"feature_id"`
+	TagID     n.NullInt64 `json:"tag_id"`
+}
+
 type DeleteBannerTask struct {
-	ID int64
+	JobID   int64              `json:"job_id"`
+	Attempt int                `json:"attempt"`
+	Filter  DeleteBannerFilter `json:"filter"`
+}
+
+// Полученная из очереди задача, которую нужно подтвердить после обработки
+type DeleteBannerDelivery struct {
+	Task    *DeleteBannerTask
+	message broker.Message
+}
+
+func (d *DeleteBannerDelivery) Ack() error {
+	return d.message.Ack()
+}
+
+func (d *DeleteBannerDelivery) Nack(requeue bool) error {
+	return d.message.Nack(requeue)
 }
 
 type BannerQueue struct {
 	queue     Queue
 	queueName string
+	mu        sync.Mutex
+	messages  <-chan broker.Message
 }
 
 func NewBannerQueue(queue Queue, queueName string) *BannerQueue {
@@ -22,26 +56,82 @@ func NewBannerQueue(queue Queue, queueName string) *BannerQueue {
 }
 
 func (q *BannerQueue) Publish(task *DeleteBannerTask) error {
+	body, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+
+	return q.queue.Publish(body, q.queueName)
+}
+
+// Публикация с задержкой, которую выдерживает брокер: отложенная задача переживает перезапуск сервиса
+func (q *BannerQueue) PublishDelayed(task *DeleteBannerTask, delay time.Duration) error {
+	body, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+
+	return q.queue.PublishDelayed(body, q.queueName, delay)
+}
+
+func encodeTask(task *DeleteBannerTask) ([]byte, error) {
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
 	if err := enc.Encode(task); err != nil {
-		return err
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
+
+// Единственная подписка на очередь, общая для всех воркеров
+func (q *BannerQueue) subscribe() (<-chan broker.Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
-	return q.queue.Publish(buf.Bytes(), q.queueName)
+	if q.messages == nil {
+		messages, err := q.queue.Consume(q.queueName)
+		if err != nil {
+			return nil, err
+		}
+		q.messages = messages
+	}
+
+	return q.messages, nil
+}
+
+func (q *BannerQueue) unsubscribe(messages <-chan broker.Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.messages == messages {
+		q.messages = nil
+	}
 }
 
-func (q *BannerQueue) Consume() (*DeleteBannerTask, error) {
-	messages, err := q.queue.Consume(q.queueName)
+func (q *BannerQueue) Consume(ctx context.Context) (*DeleteBannerDelivery, error) {
+	messages, err := q.subscribe()
 	if err != nil {
 		return nil, err
 	}
 
-	msg := <-messages
+	var msg broker.Message
+	var ok bool
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok = <-messages:
+		if !ok {
+			q.unsubscribe(messages)
+			return nil, ErrQueueClosed
+		}
+	}
+
 	var task DeleteBannerTask
-	dec := gob.NewDecoder(bytes.NewBuffer(msg))
+	dec := gob.NewDecoder(bytes.NewBuffer(msg.Body))
 	if err := dec.Decode(&task); err != nil {
+		// Битое сообщение не имеет смысла доставлять повторно
+		msg.Nack(false)
 		return nil, err
 	}
-	return &task, nil
+	return &DeleteBannerDelivery{Task: &task, message: msg}, nil
 }
@@ -0,0 +1,29 @@
+package banner
+
+import (
+	"math/rand"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Экспоненциальная задержка перед повтором номер attempt (считая с нуля).
+// Джиттер в пределах половины задержки, чтобы повторы разных задач не совпадали по времени
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 {
+		if d := p.BaseDelay << attempt; d > 0 && d < p.MaxDelay {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,39 @@
+package banner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+	}
+
+	testCases := []struct {
+		name    string
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"First retry", 0, 500 * time.Millisecond, time.Second},
+		{"Second retry", 1, time.Second, 2 * time.Second},
+		{"Third retry", 2, 2 * time.Second, 4 * time.Second},
+		{"Capped by max delay", 4, 5 * time.Second, 10 * time.Second},
+		{"Shift overflow", 100, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				delay := policy.Backoff(tc.attempt)
+				assert.GreaterOrEqual(t, delay, tc.min)
+				assert.LessOrEqual(t, delay, tc.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type ScheduledChangeRepository interface {
+	CreateScheduledChange(ctx context.Context, bannerID int64, applyAt models.UnixTime, change models.BannerPatch) (int64, error)
+	ListScheduledChanges(ctx context.Context, bannerID int64) ([]models.ScheduledChange, error)
+	CancelScheduledChange(ctx context.Context, bannerID, id int64) error
+	ClaimDueScheduledChanges(ctx context.Context, limit uint64, lockTimeout time.Duration) ([]models.ScheduledChange, error)
+	FinishScheduledChange(ctx context.Context, id int64, status models.ScheduledChangeStatus, errMsg string) error
+}
+
+// Планировщик отложенных изменений. Раз в pollInterval захватывает изменения, срок которых наступил,
+// и применяет их через BannerService.UpdateBanner, поэтому история версий и инвалидация кэша
+// работают так же, как при PATCH. Несколько инстансов не применят одно изменение дважды
+type ChangeScheduler struct {
+	service      *BannerService
+	repository   ScheduledChangeRepository
+	pollInterval time.Duration
+	lockTimeout  time.Duration
+	batchSize    uint64
+	logger       logger.Logger
+}
+
+func NewChangeScheduler(
+	service *BannerService,
+	repository ScheduledChangeRepository,
+	pollInterval time.Duration,
+	lockTimeout time.Duration,
+	batchSize int,
+	logger logger.Logger,
+) *ChangeScheduler {
+	return &ChangeScheduler{
+		service:      service,
+		repository:   repository,
+		pollInterval: pollInterval,
+		lockTimeout:  lockTimeout,
+		batchSize:    uint64(batchSize),
+		logger:       logger,
+	}
+}
+
+// Применение наступивших изменений до закрытия stopCh
+func (cs *ChangeScheduler) Run(stopCh chan struct{}) {
+	ticker := time.NewTicker(cs.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			_, err := cs.ApplyDue(context.Background())
+			if err != nil {
+				cs.logger.Error("error applying scheduled changes", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Применение всех изменений, срок которых наступил, возвращает число обработанных
+func (cs *ChangeScheduler) ApplyDue(ctx context.Context) (int, error) {
+	var total int
+	for {
+		changes, err := cs.repository.ClaimDueScheduledChanges(ctx, cs.batchSize, cs.lockTimeout)
+		if err != nil {
+			return total, err
+		}
+
+		for _, change := range changes {
+			cs.apply(ctx, change)
+		}
+		total += len(changes)
+
+		if uint64(len(changes)) < cs.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// Изменение применяется без проверки ревизии: админ запланировал его заранее и не мог знать,
+// какой будет ревизия к моменту применения. Ошибка применения сохраняется в изменении
+func (cs *ChangeScheduler) apply(ctx context.Context, change models.ScheduledChange) {
+	status := models.ScheduledChangeApplied
+	var errMsg string
+
+	_, err := cs.service.UpdateBanner(change.BannerID, n.NullInt64{}, change.Change)
+	if err != nil {
+		cs.logger.Error("error applying scheduled change",
+			slog.Int64("change_id", change.ID),
+			slog.Int64("banner_id", change.BannerID),
+			slog.String("error", err.Error()),
+		)
+		status = models.ScheduledChangeFailed
+		errMsg = err.Error()
+	}
+
+	err = cs.repository.FinishScheduledChange(ctx, change.ID, status, errMsg)
+	if err != nil {
+		// Изменение будет захвачено повторно после lockTimeout
+		cs.logger.Error("error finishing scheduled change", slog.Int64("change_id", change.ID), slog.String("error", err.Error()))
+	}
+}
+
+func (s *BannerService) ScheduleChange(bannerID int64, applyAt models.UnixTime, change models.BannerPatch) (int64, error) {
+	if change.IsZero() {
+		return 0, errs.Wrap("BannerService.ScheduleChange", "change is empty", errs.ErrRequiredValue)
+	}
+	return s.ScheduledChangeRepository.CreateScheduledChange(context.TODO(), bannerID, applyAt, change)
+}
+
+func (s *BannerService) ListScheduledChanges(bannerID int64) ([]models.ScheduledChange, error) {
+	return s.ScheduledChangeRepository.ListScheduledChanges(context.TODO(), bannerID)
+}
+
+func (s *BannerService) CancelScheduledChange(bannerID, changeID int64) error {
+	return s.ScheduledChangeRepository.CancelScheduledChange(context.TODO(), bannerID, changeID)
+}
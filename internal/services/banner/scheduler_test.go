@@ -0,0 +1,101 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeScheduler_ApplyDue(t *testing.T) {
+	s := newTestService(t,
+		models.Banner{ID: 1, Content: json.RawMessage(`{"price":100}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+	)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	scheduler := NewChangeScheduler(s.BannerService, s.scheduledChangeRepository, time.Second, time.Minute, 2, logger)
+
+	past := models.UnixTime(time.Now().Add(-time.Minute).Unix())
+	future := models.UnixTime(time.Now().Add(time.Hour).Unix())
+
+	// Баннер попадает в кэш до применения изменения
+	_, _, err := s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+
+	dueID, err := s.ScheduleChange(1, past, models.BannerPatch{Content: json.RawMessage(`{"price":90}`)})
+	require.NoError(t, err)
+	futureID, err := s.ScheduleChange(1, future, models.BannerPatch{IsActive: n.NullBool{Bool: false, Valid: true}})
+	require.NoError(t, err)
+	missingID, err := s.ScheduleChange(999, past, models.BannerPatch{IsActive: n.NullBool{Bool: false, Valid: true}})
+	require.NoError(t, err)
+
+	applied, err := scheduler.ApplyDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	t.Run("due change is applied and evicts cache", func(t *testing.T) {
+		assert.Equal(t, models.ScheduledChangeApplied, s.scheduledChangeRepository.get(dueID).Status)
+
+		banner, _, err := s.GetBanner(100, 10, false, true)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"price":90}`, string(banner.Content))
+		assert.True(t, banner.IsActive)
+	})
+
+	t.Run("failed change keeps error", func(t *testing.T) {
+		change := s.scheduledChangeRepository.get(missingID)
+		assert.Equal(t, models.ScheduledChangeFailed, change.Status)
+		assert.NotEmpty(t, change.Error)
+	})
+
+	t.Run("future change stays pending", func(t *testing.T) {
+		changes, err := s.ListScheduledChanges(1)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, futureID, changes[0].ID)
+	})
+
+	t.Run("cancelled change is not applied", func(t *testing.T) {
+		require.NoError(t, s.CancelScheduledChange(1, futureID))
+		assert.ErrorIs(t, s.CancelScheduledChange(1, futureID), errs.ErrNotFound)
+		assert.ErrorIs(t, s.CancelScheduledChange(1, dueID), errs.ErrNotFound)
+
+		changes, err := s.ListScheduledChanges(1)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("empty change is rejected", func(t *testing.T) {
+		_, err := s.ScheduleChange(1, future, models.BannerPatch{})
+		assert.ErrorIs(t, err, errs.ErrRequiredValue)
+	})
+}
+
+func TestChangeScheduler_Run(t *testing.T) {
+	s := newTestServiceWithConfig(t, func(cfg *config.BannerService) {
+		cfg.ScheduledChangesPollInterval = 10 * time.Millisecond
+		cfg.ScheduledChangesLockTimeout = time.Minute
+		cfg.ScheduledChangesBatchSize = 10
+	},
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+	)
+
+	applyAt := models.UnixTime(time.Now().Unix())
+	id, err := s.ScheduleChange(1, applyAt, models.BannerPatch{IsActive: n.NullBool{Bool: false, Valid: true}})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return s.scheduledChangeRepository.get(id).Status == models.ScheduledChangeApplied
+	}, time.Second, 10*time.Millisecond)
+
+	_, _, err = s.GetBanner(100, 10, false, true)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
@@ -0,0 +1,140 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Неизменяемый индекс всех баннеров по паре тег-фича. После публикации не меняется,
+// каждое обновление собирает новую копию, поэтому читатели работают без блокировок
+type snapshotIndex struct {
+	banners  map[string]models.Banner
+	loadedAt time.Time
+}
+
+func (idx *snapshotIndex) clone() *snapshotIndex {
+	banners := make(map[string]models.Banner, len(idx.banners))
+	for key, banner := range idx.banners {
+		banners[key] = banner
+	}
+	return &snapshotIndex{banners: banners, loadedAt: idx.loadedAt}
+}
+
+// Полный снимок баннеров в памяти для выдачи пользователям. Полностью перечитывается
+// раз в refreshInterval и точечно обновляется по изменениям баннеров. Если база недоступна,
+// продолжает отдавать последний загруженный снимок
+type BannerSnapshot struct {
+	bannerRepository BannerRepository
+	refreshInterval  time.Duration
+	index            atomic.Pointer[snapshotIndex]
+	// Обновления снимка выполняются по одному, чтобы полная перезагрузка не затёрла точечное изменение
+	mu     sync.Mutex
+	logger logger.Logger
+}
+
+func NewBannerSnapshot(bannerRepository BannerRepository, refreshInterval time.Duration, logger logger.Logger) *BannerSnapshot {
+	return &BannerSnapshot{
+		bannerRepository: bannerRepository,
+		refreshInterval:  refreshInterval,
+		logger:           logger,
+	}
+}
+
+// Загрузка снимка и периодическое перечитывание до закрытия stopCh
+func (bs *BannerSnapshot) Run(stopCh chan struct{}) {
+	ticker := time.NewTicker(bs.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		err := bs.Reload(context.Background())
+		if err != nil {
+			bs.logger.Error("error reloading banner snapshot", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (bs *BannerSnapshot) Reload(ctx context.Context) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	banners, err := bs.bannerRepository.ListBanners(ctx, n.NullInt64{}, n.NullInt64{}, n.NullUint64{}, n.NullUint64{})
+	if err != nil {
+		return err
+	}
+
+	idx := &snapshotIndex{
+		banners:  make(map[string]models.Banner, len(banners)),
+		loadedAt: time.Now(),
+	}
+	for _, banner := range banners {
+		for _, tagID := range banner.TagIds {
+			idx.banners[cacheKey(tagID, banner.FeatureID)] = banner
+		}
+	}
+
+	bs.index.Store(idx)
+	bs.logger.Debug("banner snapshot reloaded", slog.Int("banners", len(banners)))
+	return nil
+}
+
+// Точечное обновление снимка: пары из изменения убираются, а текущее состояние баннера перечитывается из базы
+func (bs *BannerSnapshot) Apply(ctx context.Context, change models.BannerChange) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	current := bs.index.Load()
+	// Снимок ещё не загружен, первая загрузка и так увидит изменение
+	if current == nil {
+		return nil
+	}
+
+	banner, err := bs.bannerRepository.GetBannerByID(ctx, change.BannerID)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return err
+	}
+
+	next := current.clone()
+	for _, featureID := range change.FeatureIDs {
+		for _, tagID := range change.TagIDs {
+			key := cacheKey(tagID, featureID)
+			// Пару мог уже занять другой баннер, его запись не трогаем
+			if next.banners[key].ID == change.BannerID {
+				delete(next.banners, key)
+			}
+		}
+	}
+	if err == nil {
+		for _, tagID := range banner.TagIds {
+			next.banners[cacheKey(tagID, banner.FeatureID)] = banner
+		}
+	}
+
+	bs.index.Store(next)
+	return nil
+}
+
+// ok = false, пока снимок не загружен, и тогда ответ нужно искать в кэше или базе.
+// После загрузки снимок полный: отсутствие пары в нём означает, что баннера нет
+func (bs *BannerSnapshot) Get(tagID, featureID int64) (banner models.Banner, found bool, ok bool) {
+	idx := bs.index.Load()
+	if idx == nil {
+		return models.Banner{}, false, false
+	}
+
+	banner, found = idx.banners[cacheKey(tagID, featureID)]
+	return banner, found, true
+}
@@ -0,0 +1,114 @@
+package banner
+
+import (
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBannerSnapshot(t *testing.T) {
+	repo := newFakeBannerRepository(
+		models.Banner{ID: 1, Content: json.RawMessage(`{}`), IsActive: true, FeatureID: 10, TagIds: []int64{100, 200}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{}`), IsActive: false, FeatureID: 20, TagIds: []int64{100}},
+	)
+	snapshot := NewBannerSnapshot(repo, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, _, ok := snapshot.Get(100, 10)
+	assert.False(t, ok)
+
+	require.NoError(t, snapshot.Reload(context.Background()))
+
+	t.Run("serves every pair", func(t *testing.T) {
+		for _, key := range [][2]int64{{100, 10}, {200, 10}, {100, 20}} {
+			_, found, ok := snapshot.Get(key[0], key[1])
+			assert.True(t, ok)
+			assert.True(t, found, key)
+		}
+
+		_, found, ok := snapshot.Get(300, 10)
+		assert.True(t, ok)
+		assert.False(t, found)
+	})
+
+	t.Run("applies remap", func(t *testing.T) {
+		_, err := repo.UpdateBanner(context.Background(), 1, n.NullInt64{}, models.BannerPatch{TagIDs: []int64{300}})
+		require.NoError(t, err)
+		require.NoError(t, snapshot.Apply(context.Background(), models.BannerChange{
+			BannerID: 1, FeatureIDs: []int64{10}, TagIDs: []int64{100, 200, 300},
+		}))
+
+		_, found, _ := snapshot.Get(100, 10)
+		assert.False(t, found)
+		banner, found, _ := snapshot.Get(300, 10)
+		assert.True(t, found)
+		assert.Equal(t, int64(1), banner.ID)
+	})
+
+	t.Run("applies delete without touching other banners", func(t *testing.T) {
+		require.NoError(t, repo.DeleteBanner(context.Background(), 1))
+		require.NoError(t, snapshot.Apply(context.Background(), models.BannerChange{
+			BannerID: 1, FeatureIDs: []int64{10, 20}, TagIDs: []int64{100, 300},
+		}))
+
+		_, found, _ := snapshot.Get(300, 10)
+		assert.False(t, found)
+		_, found, _ = snapshot.Get(100, 20)
+		assert.True(t, found)
+	})
+
+	t.Run("keeps serving while database is down", func(t *testing.T) {
+		repo.listErr = errors.New("database is down")
+		assert.Error(t, snapshot.Reload(context.Background()))
+
+		_, found, ok := snapshot.Get(100, 20)
+		assert.True(t, ok)
+		assert.True(t, found)
+	})
+}
+
+func TestBannerService_GetBannerFromSnapshot(t *testing.T) {
+	s := newTestServiceWithConfig(t, func(cfg *config.BannerService) {
+		cfg.SnapshotEnabled = true
+		cfg.SnapshotRefreshInterval = time.Minute
+	},
+		models.Banner{ID: 1, Content: json.RawMessage(`{"title":"old"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100}},
+		models.Banner{ID: 2, Content: json.RawMessage(`{}`), IsActive: false, FeatureID: 20, TagIds: []int64{200}},
+	)
+
+	require.Eventually(t, func() bool {
+		_, _, ok := s.Snapshot.Get(100, 10)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	banner, _, err := s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), banner.ID)
+
+	_, _, err = s.GetBanner(200, 20, false, true)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	_, _, err = s.GetBanner(200, 20, false, false)
+	assert.NoError(t, err)
+
+	_, _, err = s.GetBanner(300, 30, false, true)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	assert.Equal(t, int64(0), s.bannerRepository.getBannerCalls.Load())
+
+	// Свои изменения видны сразу
+	_, err = s.UpdateBanner(1, n.NullInt64{}, models.BannerPatch{Content: json.RawMessage(`{"title":"new"}`)})
+	require.NoError(t, err)
+	banner, _, err = s.GetBanner(100, 10, false, true)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title":"new"}`, string(banner.Content))
+}
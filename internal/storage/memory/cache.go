@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type cacheItem struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (i cacheItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
+
+// Потокобезопасный кэш в памяти процесса с TTL, замена Redis для локального запуска и тестов
+type Cache struct {
+	mu    sync.RWMutex
+	items map[string]cacheItem
+	done  chan struct{}
+}
+
+func NewCache(cleanupInterval time.Duration) *Cache {
+	c := &Cache{
+		items: make(map[string]cacheItem),
+		done:  make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go c.cleanup(cleanupInterval)
+	}
+
+	return c
+}
+
+// Периодическое удаление просроченных записей, чтобы кэш не рос бесконечно
+func (c *Cache) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			for key, item := range c.items {
+				if item.expired(now) {
+					delete(c.items, key)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// Нулевой ttl означает запись без срока жизни, как в Redis
+func (c *Cache) Push(key, value string, ttl time.Duration) error {
+	item := cacheItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	c.items[key] = item
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *Cache) Get(key string) (string, error) {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if !ok || item.expired(time.Now()) {
+		return "", errs.ErrNotFound
+	}
+
+	return item.value, nil
+}
+
+func (c *Cache) GetMany(keys []string) (map[string]string, error) {
+	now := time.Now()
+	values := make(map[string]string, len(keys))
+
+	c.mu.RLock()
+	for _, key := range keys {
+		if item, ok := c.items[key]; ok && !item.expired(now) {
+			values[key] = item.value
+		}
+	}
+	c.mu.RUnlock()
+
+	return values, nil
+}
+
+func (c *Cache) Remove(key string) error {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Как INCR + EXPIRE в Redis: срок жизни задаётся только новому счётчику
+func (c *Cache) Incr(key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok || item.expired(now) {
+		item = cacheItem{value: "0"}
+		if ttl > 0 {
+			item.expiresAt = now.Add(ttl)
+		}
+	}
+
+	value, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	value++
+	item.value = strconv.FormatInt(value, 10)
+	c.items[key] = item
+
+	return value, nil
+}
+
+func (c *Cache) Close() error {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+	return nil
+}
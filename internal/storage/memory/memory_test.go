@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache(10 * time.Millisecond)
+	defer cache.Close()
+
+	require.NoError(t, cache.Push("key", "value", time.Hour))
+	value, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	// Перезапись значения
+	require.NoError(t, cache.Push("key", "new_value", time.Hour))
+	value, err = cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "new_value", value)
+
+	// Удаление
+	require.NoError(t, cache.Remove("key"))
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	// Истечение TTL
+	require.NoError(t, cache.Push("expiring", "value", 20*time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+	_, err = cache.Get("expiring")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	cache.mu.RLock()
+	assert.NotContains(t, cache.items, "expiring")
+	cache.mu.RUnlock()
+
+	// Запись без срока жизни
+	require.NoError(t, cache.Push("forever", "value", 0))
+	value, err = cache.Get("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestCacheIncr(t *testing.T) {
+	cache := NewCache(0)
+	defer cache.Close()
+
+	for want := int64(1); want <= 3; want++ {
+		value, err := cache.Incr("counter", 30*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, want, value)
+	}
+
+	// Срок жизни не продлевается увеличением, после истечения счёт начинается заново
+	time.Sleep(50 * time.Millisecond)
+	value, err := cache.Incr("counter", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	require.NoError(t, cache.Push("text", "value", time.Hour))
+	_, err = cache.Incr("text", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestQueue(t *testing.T) {
+	queue := NewQueue(2)
+
+	messages, err := queue.Consume("queue")
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Publish([]byte("first"), "queue"))
+
+	msg := <-messages
+	assert.Equal(t, []byte("first"), msg.Body)
+	assert.NoError(t, msg.Ack())
+
+	// Nack с requeue возвращает сообщение в очередь
+	require.NoError(t, queue.Publish([]byte("second"), "queue"))
+	msg = <-messages
+	assert.NoError(t, msg.Nack(true))
+	msg = <-messages
+	assert.Equal(t, []byte("second"), msg.Body)
+
+	// Отложенное сообщение приходит не раньше задержки
+	published := time.Now()
+	require.NoError(t, queue.PublishDelayed([]byte("delayed"), "queue", 50*time.Millisecond))
+	msg = <-messages
+	assert.Equal(t, []byte("delayed"), msg.Body)
+	assert.GreaterOrEqual(t, time.Since(published), 50*time.Millisecond)
+
+	// Переполнение буфера без потребителя
+	require.NoError(t, queue.Publish([]byte("1"), "other"))
+	require.NoError(t, queue.Publish([]byte("2"), "other"))
+	assert.ErrorIs(t, queue.Publish([]byte("3"), "other"), ErrQueueFull)
+
+	require.NoError(t, queue.Close())
+	assert.ErrorIs(t, queue.Publish([]byte("4"), "queue"), ErrQueueClosed)
+
+	_, ok := <-messages
+	assert.False(t, ok)
+}
+
+func TestLRU(t *testing.T) {
+	t.Run("evicts least recently used", func(t *testing.T) {
+		lru := NewLRU[string, int](2, 0)
+
+		lru.Set("a", 1)
+		lru.Set("b", 2)
+		_, ok := lru.Get("a")
+		require.True(t, ok)
+		lru.Set("c", 3)
+
+		_, ok = lru.Get("b")
+		assert.False(t, ok)
+		value, ok := lru.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+		value, ok = lru.Get("c")
+		assert.True(t, ok)
+		assert.Equal(t, 3, value)
+
+		assert.Equal(t, LRUStats{Hits: 3, Misses: 1, Evictions: 1, Size: 2}, lru.Stats())
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		lru := NewLRU[string, int](10, 20*time.Millisecond)
+
+		lru.Set("a", 1)
+		time.Sleep(40 * time.Millisecond)
+
+		_, ok := lru.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Stats().Size)
+	})
+
+	t.Run("removes entries", func(t *testing.T) {
+		lru := NewLRU[string, int](10, 0)
+
+		lru.Set("a", 1)
+		lru.Set("a", 2)
+		value, ok := lru.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 2, value)
+
+		lru.Remove("a")
+		_, ok = lru.Get("a")
+		assert.False(t, ok)
+	})
+}
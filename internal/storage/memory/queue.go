@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"backend-trainee-assignment-2024/internal/broker"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("queue is full")
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+// Очередь на каналах в памяти процесса, замена RabbitMQ для локального запуска и тестов.
+// Сообщения не переживают перезапуск, Nack с requeue возвращает сообщение в конец очереди
+type Queue struct {
+	mu         sync.Mutex
+	queues     map[string]chan []byte
+	bufferSize int
+	done       chan struct{}
+}
+
+func NewQueue(bufferSize int) *Queue {
+	return &Queue{
+		queues:     make(map[string]chan []byte),
+		bufferSize: bufferSize,
+		done:       make(chan struct{}),
+	}
+}
+
+func (q *Queue) getQueue(name string) chan []byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ch, ok := q.queues[name]
+	if !ok {
+		ch = make(chan []byte, q.bufferSize)
+		q.queues[name] = ch
+	}
+	return ch
+}
+
+func (q *Queue) Publish(message []byte, queue string) error {
+	select {
+	case <-q.done:
+		return ErrQueueClosed
+	default:
+	}
+
+	select {
+	case q.getQueue(queue) <- message:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Задержка выдерживается таймером в памяти, как и сами сообщения, она не переживает перезапуск
+func (q *Queue) PublishDelayed(message []byte, queue string, delay time.Duration) error {
+	select {
+	case <-q.done:
+		return ErrQueueClosed
+	default:
+	}
+
+	time.AfterFunc(delay, func() {
+		q.Publish(message, queue)
+	})
+	return nil
+}
+
+func (q *Queue) Consume(queue string) (<-chan broker.Message, error) {
+	select {
+	case <-q.done:
+		return nil, ErrQueueClosed
+	default:
+	}
+
+	ch := q.getQueue(queue)
+	messages := make(chan broker.Message)
+
+	go func() {
+		defer close(messages)
+
+		for {
+			select {
+			case <-q.done:
+				return
+			case body := <-ch:
+				msg := broker.NewMessage(
+					body,
+					nil,
+					func(requeue bool) error {
+						if requeue {
+							return q.Publish(body, queue)
+						}
+						return nil
+					},
+				)
+
+				select {
+				case <-q.done:
+					return
+				case messages <- msg:
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+func (q *Queue) Close() error {
+	select {
+	case <-q.done:
+	default:
+		close(q.done)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Подписка на канал LISTEN/NOTIFY. Под подписку забирается отдельное соединение из пула,
+// потому что уведомления приходят только в то соединение, которое выполнило LISTEN.
+// Блокируется до отмены ctx или ошибки соединения, переподключение остаётся вызывающему
+func (p *Postgres) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	poolConn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("database.Postgres.Listen error in Acquire: %w", err)
+	}
+	// Соединение забирается из пула насовсем, чтобы подписка не досталась другим запросам
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		return fmt.Errorf("database.Postgres.Listen error in LISTEN: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("database.Postgres.Listen error in WaitForNotification: %w", err)
+		}
+		handle(notification.Payload)
+	}
+}
+
+// Отправка уведомления в рамках транзакции: подписчики получат его только после коммита
+func Notify(ctx context.Context, tx pgx.Tx, channel, payload string) error {
+	_, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	if err != nil {
+		return fmt.Errorf("database.Notify error: %w", err)
+	}
+	return nil
+}
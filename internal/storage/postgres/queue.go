@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"backend-trainee-assignment-2024/internal/broker"
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/logger"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Очередь задач в таблице queue_messages. Сообщение забирается через FOR UPDATE SKIP LOCKED
+// и становится невидимым для других потребителей на visibilityTimeout. Если его не подтвердили
+// за это время (например, сервис упал), сообщение будет доставлено повторно
+type PostgresQueue struct {
+	db                *Postgres
+	pollInterval      time.Duration
+	visibilityTimeout time.Duration
+	done              chan struct{}
+	logger            logger.Logger
+}
+
+func NewPostgresQueue(db *Postgres, cfg config.Queue, logger logger.Logger) *PostgresQueue {
+	return &PostgresQueue{
+		db:                db,
+		pollInterval:      cfg.PollInterval,
+		visibilityTimeout: cfg.VisibilityTimeout,
+		done:              make(chan struct{}),
+		logger:            logger,
+	}
+}
+
+func (q *PostgresQueue) Publish(message []byte, queue string) error {
+	_, err := q.db.Exec(context.Background(), "INSERT INTO queue_messages (queue, body) VALUES ($1, $2)", queue, message)
+	if err != nil {
+		return fmt.Errorf("database.PostgresQueue.Publish error: %w", err)
+	}
+	return nil
+}
+
+// Отложенное сообщение сразу считается захваченным до момента, когда его можно доставить
+func (q *PostgresQueue) PublishDelayed(message []byte, queue string, delay time.Duration) error {
+	_, err := q.db.Exec(context.Background(),
+		"INSERT INTO queue_messages (queue, body, locked_until) VALUES ($1, $2, now() + make_interval(secs => $3))",
+		queue, message, delay.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("database.PostgresQueue.PublishDelayed error: %w", err)
+	}
+	return nil
+}
+
+func (q *PostgresQueue) Consume(queue string) (<-chan broker.Message, error) {
+	messages := make(chan broker.Message)
+	go q.poll(queue, messages)
+
+	return messages, nil
+}
+
+func (q *PostgresQueue) poll(queue string, messages chan<- broker.Message) {
+	defer close(messages)
+
+	for {
+		id, body, err := q.claim(queue)
+		if err != nil {
+			// Пустая очередь — обычное состояние, а ошибку базы не должно быть видно как простой
+			if !IfErrNoRows(err) {
+				q.logger.Error("error claiming queue message", slog.String("queue", queue), slog.String("error", err.Error()))
+			}
+			select {
+			case <-q.done:
+				return
+			case <-time.After(q.pollInterval):
+			}
+			continue
+		}
+
+		select {
+		case <-q.done:
+			q.release(id)
+			return
+		case messages <- broker.NewMessage(
+			body,
+			func() error { return q.ack(id) },
+			func(requeue bool) error { return q.nack(id, requeue) },
+		):
+		}
+	}
+}
+
+func (q *PostgresQueue) claim(queue string) (int64, []byte, error) {
+	var id int64
+	var body []byte
+
+	err := q.db.QueryRow(context.Background(), `
+		UPDATE queue_messages SET locked_until = now() + make_interval(secs => $2)
+		WHERE id = (
+			SELECT id FROM queue_messages
+			WHERE queue = $1 AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, body`,
+		queue, q.visibilityTimeout.Seconds(),
+	).Scan(&id, &body)
+
+	return id, body, err
+}
+
+func (q *PostgresQueue) ack(id int64) error {
+	_, err := q.db.Exec(context.Background(), "DELETE FROM queue_messages WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("database.PostgresQueue.ack error: %w", err)
+	}
+	return nil
+}
+
+func (q *PostgresQueue) nack(id int64, requeue bool) error {
+	if !requeue {
+		return q.ack(id)
+	}
+	return q.release(id)
+}
+
+func (q *PostgresQueue) release(id int64) error {
+	_, err := q.db.Exec(context.Background(), "UPDATE queue_messages SET locked_until = NULL WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("database.PostgresQueue.release error: %w", err)
+	}
+	return nil
+}
+
+func (q *PostgresQueue) Close() error {
+	select {
+	case <-q.done:
+	default:
+		close(q.done)
+	}
+	return nil
+}
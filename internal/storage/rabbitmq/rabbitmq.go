@@ -1,98 +1,315 @@
 package rabbitmq
 
 import (
+	"backend-trainee-assignment-2024/internal/broker"
 	"backend-trainee-assignment-2024/internal/config"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var ErrNotConnected = errors.New("rabbitmq is not connected")
+
+// Суффикс очереди, в которой отложенные сообщения ждут истечения TTL
+const delayedQueueSuffix = "_delayed"
+
 type RabbitMQQueue struct {
+	url            string
+	queueName      string
+	prefetchCount  int
+	reconnectDelay time.Duration
+
+	mu         sync.Mutex
 	connection *amqp.Connection
 	channel    *amqp.Channel
-	queueName  string
+	// Закрывается, когда соединение установлено, и пересоздаётся при его потере
+	ready chan struct{}
+	done  chan struct{}
 }
 
 func NewRabbitMQQueue(cfg config.RabbitMQ) (*RabbitMQQueue, error) {
-	url := fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.User, cfg.Password, cfg.Address, cfg.Port)
+	q := &RabbitMQQueue{
+		url:            fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.User, cfg.Password, cfg.Address, cfg.Port),
+		queueName:      cfg.Name,
+		prefetchCount:  cfg.PrefetchCount,
+		reconnectDelay: cfg.ReconnectDelay,
+		ready:          make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	if err := q.connect(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
 
-	conn, err := amqp.Dial(url)
+func (q *RabbitMQQueue) connect() error {
+	conn, err := amqp.Dial(q.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
+	ch, err := q.openChannel(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	q.mu.Lock()
+	q.connection = conn
+	q.channel = ch
+	close(q.ready)
+	q.mu.Unlock()
+
+	go q.watch(conn, ch)
+
+	return nil
+}
+
+func (q *RabbitMQQueue) openChannel(conn *amqp.Connection) (*amqp.Channel, error) {
 	ch, err := conn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RabbitMQ channel: %w", err)
 	}
 
+	err = ch.Qos(q.prefetchCount, 0, false)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to set RabbitMQ prefetch: %w", err)
+	}
+
+	// Очередь durable, чтобы задачи переживали перезапуск брокера. Существующую не-durable очередь
+	// с тем же именем брокер переобъявить не даст, поэтому у durable-очереди своё имя в конфигурации
 	_, err = ch.QueueDeclare(
-		cfg.Name,
-		false,
+		q.queueName,
+		true,
 		false,
 		false,
 		false,
 		nil,
 	)
 	if err != nil {
+		ch.Close()
 		return nil, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
-	return &RabbitMQQueue{
-		connection: conn,
-		channel:    ch,
-		queueName:  cfg.Name,
-	}, nil
+	// У отложенных сообщений нет потребителей: по истечении TTL сообщения брокер
+	// перекладывает его в основную очередь через default exchange
+	_, err = ch.QueueDeclare(
+		q.queueName+delayedQueueSuffix,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": q.queueName,
+		},
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to declare delayed queue: %w", err)
+	}
+
+	return ch, nil
+}
+
+// Ожидание закрытия канала или соединения и восстановление. Брокер может закрыть только канал
+// (например, при подтверждении сообщения с устаревшим delivery tag), тогда соединение переиспользуется
+func (q *RabbitMQQueue) watch(conn *amqp.Connection, ch *amqp.Channel) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	select {
+	case <-q.done:
+		return
+	case <-connClosed:
+	case <-chClosed:
+	}
+
+	q.mu.Lock()
+	q.ready = make(chan struct{})
+	q.mu.Unlock()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-time.After(q.reconnectDelay):
+		}
+
+		if !conn.IsClosed() {
+			ch, err := q.openChannel(conn)
+			if err == nil {
+				q.mu.Lock()
+				q.channel = ch
+				close(q.ready)
+				q.mu.Unlock()
+
+				go q.watch(conn, ch)
+				return
+			}
+			conn.Close()
+		}
+
+		if err := q.connect(); err == nil {
+			return
+		}
+	}
+}
+
+// Текущий канал, если соединение установлено. Если wait, то ждёт переподключения
+func (q *RabbitMQQueue) getChannel(wait bool) (*amqp.Channel, error) {
+	q.mu.Lock()
+	ready := q.ready
+	q.mu.Unlock()
+
+	if !wait {
+		select {
+		case <-ready:
+		default:
+			return nil, ErrNotConnected
+		}
+	}
+
+	select {
+	case <-q.done:
+		return nil, ErrNotConnected
+	case <-ready:
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil, ErrNotConnected
+	default:
+		return q.channel, nil
+	}
 }
 
 func (q *RabbitMQQueue) Publish(message []byte, queue string) error {
-	return q.channel.PublishWithContext(
+	ch, err := q.getChannel(false)
+	if err != nil {
+		return err
+	}
+
+	return ch.PublishWithContext(
 		context.Background(),
 		"",
 		queue,
 		false,
 		false,
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        message,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         message,
 		})
 }
 
-func (q *RabbitMQQueue) Consume(queue string) (<-chan []byte, error) {
-	msgs, err := q.channel.Consume(
-		queue,
+// Отложенная публикация через очередь с TTL. TTL проверяется только у сообщения в голове очереди,
+// поэтому короткая задержка может дождаться истечения более длинной, стоящей перед ней
+func (q *RabbitMQQueue) PublishDelayed(message []byte, queue string, delay time.Duration) error {
+	ch, err := q.getChannel(false)
+	if err != nil {
+		return err
+	}
+
+	return ch.PublishWithContext(
+		context.Background(),
 		"",
-		true,
-		false,
+		queue+delayedQueueSuffix,
 		false,
 		false,
-		nil,
-	)
-	if err != nil {
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Expiration:   strconv.FormatInt(delay.Milliseconds(), 10),
+			Body:         message,
+		})
+}
+
+// Подписка на очередь. Канал сообщений общий на всё время жизни RabbitMQQueue:
+// после переподключения к брокеру подписка восстанавливается автоматически
+func (q *RabbitMQQueue) Consume(queue string) (<-chan broker.Message, error) {
+	if _, err := q.getChannel(false); err != nil {
 		return nil, err
 	}
 
-	messages := make(chan []byte, 100)
-	go func() {
-		for msg := range msgs {
-			messages <- msg.Body
-		}
-	}()
+	messages := make(chan broker.Message)
+	go q.consume(queue, messages)
 
 	return messages, nil
 }
 
+func (q *RabbitMQQueue) consume(queue string, messages chan<- broker.Message) {
+	defer close(messages)
+
+	for {
+		ch, err := q.getChannel(true)
+		if err != nil {
+			return
+		}
+
+		deliveries, err := ch.Consume(
+			queue,
+			"",
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+		if err != nil {
+			select {
+			case <-q.done:
+				return
+			case <-time.After(q.reconnectDelay):
+			}
+			continue
+		}
+
+		// Канал deliveries закрывается при потере канала или соединения
+		for d := range deliveries {
+			select {
+			case <-q.done:
+				return
+			case messages <- broker.NewMessage(
+				d.Body,
+				func() error { return d.Ack(false) },
+				func(requeue bool) error { return d.Nack(false, requeue) },
+			):
+			}
+		}
+	}
+}
+
 func (q *RabbitMQQueue) Close() error {
-	err := q.channel.Close()
-	if err != nil {
-		return err
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	default:
+		close(q.done)
+	}
+
+	if q.connection == nil || q.connection.IsClosed() {
+		return nil
 	}
 
-	err = q.connection.Close()
+	err := q.channel.Close()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return q.connection.Close()
 }
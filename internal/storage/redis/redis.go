@@ -11,32 +11,34 @@ import (
 )
 
 type Redis struct {
-	client      *redis.Client
-	ConnTimeout time.Duration
+	client    *redis.Client
+	OpTimeout time.Duration
 }
 
 func NewRedis(cfg config.Redis) *Redis {
 	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Address + ":" + strconv.Itoa(cfg.Port),
-		Password: cfg.Password,
-		DB:       0,
+		Addr:        cfg.Address + ":" + strconv.Itoa(cfg.Port),
+		Password:    cfg.Password,
+		DB:          0,
+		DialTimeout: cfg.ConnTimeout,
 	})
 
+	// Таймаут одной операции короче таймаута подключения: медленный Redis не должен задерживать запросы
 	return &Redis{
-		client:      client,
-		ConnTimeout: cfg.ConnTimeout,
+		client:    client,
+		OpTimeout: cfg.OpTimeout,
 	}
 }
 
 func (c *Redis) Push(key, value string, ttl time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), c.ConnTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.OpTimeout)
 	defer cancel()
 
 	return c.client.Set(ctx, key, value, ttl).Err()
 }
 
 func (c *Redis) Get(key string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), c.ConnTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.OpTimeout)
 	defer cancel()
 
 	value, err := c.client.Get(ctx, key).Result()
@@ -45,13 +47,61 @@ func (c *Redis) Get(key string) (string, error) {
 	}
 	return value, err
 }
+
+// Все GET отправляются одним пайплайном, за один сетевой обмен
+func (c *Redis) GetMany(keys []string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.OpTimeout)
+	defer cancel()
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[keys[i]] = value
+	}
+	return values, nil
+}
+
 func (c *Redis) Remove(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), c.ConnTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.OpTimeout)
 	defer cancel()
 
 	return c.client.Del(ctx, key).Err()
 }
 
+// INCR и EXPIRE NX отправляются одной транзакцией: срок жизни задаётся только новому счётчику
+func (c *Redis) Incr(key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.OpTimeout)
+	defer cancel()
+
+	var incr *redis.IntCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
 func (c *Redis) Close() error {
 	return c.client.Close()
 }
@@ -8,6 +8,7 @@ import (
 
 	"backend-trainee-assignment-2024/internal/errs"
 	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
 	n "backend-trainee-assignment-2024/internal/nullable"
 
 	"github.com/go-chi/chi/v5"
@@ -70,11 +71,13 @@ func (h *GetBannerForUser) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userType, err := h.AuthService.ValidateToken(token)
+	// Токен разбирается один раз: из него берутся роль, пользователь и значения для плейсхолдеров
+	claims, err := h.AuthService.Claims(token)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	userType, userID := claimsUser(claims)
 
 	if useLastRevision && userType != AdminRole {
 		w.WriteHeader(http.StatusForbidden)
@@ -87,7 +90,7 @@ func (h *GetBannerForUser) Handle(w http.ResponseWriter, r *http.Request) {
 		onlyActive = true
 	}
 
-	banner, err := h.BannerService.GetBanner(tagID, featureID, useLastRevision, onlyActive)
+	banner, degraded, err := h.BannerService.GetBanner(tagID, featureID, useLastRevision, onlyActive)
 	if err != nil {
 		if errors.Is(err, errs.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -98,9 +101,67 @@ func (h *GetBannerForUser) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(banner.Content)
+	// Свежие данные и данные при недоступной базе клиент должен перепроверять,
+	// остальное можно держать столько же, сколько баннер живёт в кэше сервиса.
+	// Баннер с ограничением частоты тоже перепроверяется, чтобы каждый новый показ проходил через сервис
+	cacheControl := maxAgeCacheControl(h.BannerService.CacheMaxAge(banner))
+	if useLastRevision || degraded || banner.FrequencyCap != nil {
+		cacheControl = noCacheControl
+	}
+
+	// Перевод заменяет основное содержимое, варианты не переводятся
+	locale, localized := h.BannerService.Localize(banner, requestLocales(r))
+	banner.Content = localized
+
+	// Вариант закрепляется за пользователем, поэтому ответ зависит от токена
+	variant, content := banner.PickVariant(userID)
+
+	// Содержимое с плейсхолдерами собирается под каждый запрос и не кэшируется клиентом
+	templated := models.HasTemplate(content)
+	if templated {
+		content, err = renderContent(claims, r, content)
+		if err != nil {
+			h.logger.Error(err.Error())
+			http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+			return
+		}
+		cacheControl = noCacheControl
+	}
+
+	etag := computeETag(content)
+
+	// Исчерпавший ограничение пользователь получает то же, что при отсутствии баннера.
+	// Показы админам не считаются. Перепроверка по ETag не считается новым показом, но после
+	// исчерпания ограничения тоже получает 404, иначе клиент продолжит показывать копию из своего кэша
+	if userType == UserRole {
+		var allowed bool
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			allowed = !h.BannerService.ImpressionCapped(banner, userID)
+		} else {
+			allowed = h.BannerService.AllowImpression(banner, userID)
+		}
+		if !allowed {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set(VariantHeader, variant)
+	// База недоступна, баннер из последнего сохранённого состояния
+	if degraded {
+		w.Header().Set(DegradedHeader, "true")
+	}
+	if len(banner.Variants) > 0 || templated {
+		w.Header().Add("Vary", "token")
+	}
+	if len(banner.LocalizedContent) > 0 {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	if locale != "" && variant == models.DefaultVariantName {
+		w.Header().Set("Content-Language", locale)
+	}
+
+	writeJSONWithETag(w, r, content, etag, cacheControl)
 }
 
 type ListBanners struct {
@@ -176,12 +237,19 @@ func (h *ListBanners) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-
+	var body []byte
 	if len(banners) > 0 {
-		json.NewEncoder(w).Encode(banners)
+		body, err = json.Marshal(banners)
+		if err != nil {
+			h.logger.Error(err.Error())
+			http.Error(w, InternalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+		body = append(body, '\n')
 	}
+
+	// Список читается из базы мимо кэша, поэтому клиент перепроверяет его при каждом запросе
+	writeJSONWithETag(w, r, body, computeETag(body), noCacheControl)
 }
 
 type CreateBanner struct {
@@ -212,14 +280,7 @@ func (h *CreateBanner) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type bannerData struct {
-		TagIDs    []int64         `json:"tag_ids"`
-		FeatureID int64           `json:"feature_id"`
-		Content   json.RawMessage `json:"content"`
-		IsActive  bool            `json:"is_active"`
-	}
-
-	var banner bannerData
+	var banner models.BannerInput
 	if err := json.NewDecoder(r.Body).Decode(&banner); err != nil {
 		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
 		return
@@ -233,9 +294,37 @@ func (h *CreateBanner) Handle(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, ErrorResponse("tag_ids array cannot be empty"), http.StatusBadRequest)
 		return
 	}
+	if banner.ActiveWindow.Empty() {
+		http.Error(w, ErrorResponse(InvalidActiveWindowMsg), http.StatusBadRequest)
+		return
+	}
+	if validateVariants(banner.Variants) != nil {
+		http.Error(w, ErrorResponse(InvalidVariantsMsg), http.StatusBadRequest)
+		return
+	}
+	if banner.FrequencyCap != nil && !banner.FrequencyCap.Valid() {
+		http.Error(w, ErrorResponse(InvalidFrequencyCapMsg), http.StatusBadRequest)
+		return
+	}
+	if validateLocalizedContent(banner.LocalizedContent, false) != nil {
+		http.Error(w, ErrorResponse(InvalidLocalizedContentMsg), http.StatusBadRequest)
+		return
+	}
+	if err := validateTemplates(banner.Content, banner.Variants, banner.LocalizedContent); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
 
-	bannerID, err := h.BannerService.CreateBanner(banner.TagIDs, banner.FeatureID, banner.Content, banner.IsActive)
+	bannerID, err := h.BannerService.CreateBanner(banner)
 	if err != nil {
+		if errors.Is(err, errs.ErrInvalidVariants) {
+			http.Error(w, ErrorResponse(InvalidVariantsMsg), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, errs.ErrInvalidValue) {
+			http.Error(w, ErrorResponse(InvalidActiveWindowMsg), http.StatusBadRequest)
+			return
+		}
 		h.logger.Error(err.Error())
 		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
 		return
@@ -272,13 +361,6 @@ func (h *UpdateBanner) validate(idStr string) (int64, error) {
 func (h *UpdateBanner) Handle(w http.ResponseWriter, r *http.Request) {
 	token := r.Header.Get("token")
 
-	type bannerPatchData struct {
-		TagIDs    []int64         `json:"tag_ids"`
-		FeatureID n.NullInt64     `json:"feature_id"`
-		Content   json.RawMessage `json:"content"`
-		IsActive  n.NullBool      `json:"is_active"`
-	}
-
 	bannerID, err := h.validate(
 		chi.URLParam(r, "bannerID"),
 	)
@@ -299,16 +381,57 @@ func (h *UpdateBanner) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var bannerData bannerPatchData
+	// Изменение без If-Match могло бы затереть чужие правки
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		http.Error(w, ErrorResponse(PreconditionRequiredMsg), http.StatusPreconditionRequired)
+		return
+	}
+	expectedRevision, err := parseIfMatch(ifMatch)
+	if err != nil {
+		http.Error(w, ErrorResponse(fmt.Errorf("validate If-Match: %w", err).Error()), http.StatusBadRequest)
+		return
+	}
+
+	var bannerData models.BannerPatch
 
 	if err := json.NewDecoder(r.Body).Decode(&bannerData); err != nil {
 		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.BannerService.UpdateBanner(bannerID, bannerData.TagIDs, bannerData.FeatureID, bannerData.Content, bannerData.IsActive); err != nil {
+	if bannerData.ActiveWindow.Empty() {
+		http.Error(w, ErrorResponse(InvalidActiveWindowMsg), http.StatusBadRequest)
+		return
+	}
+	if validateVariants(bannerData.Variants) != nil {
+		http.Error(w, ErrorResponse(InvalidVariantsMsg), http.StatusBadRequest)
+		return
+	}
+	if bannerData.FrequencyCap != nil && !bannerData.FrequencyCap.Valid() {
+		http.Error(w, ErrorResponse(InvalidFrequencyCapMsg), http.StatusBadRequest)
+		return
+	}
+	if validateLocalizedContent(bannerData.LocalizedContent, true) != nil {
+		http.Error(w, ErrorResponse(InvalidLocalizedContentMsg), http.StatusBadRequest)
+		return
+	}
+	if err := validateTemplates(bannerData.Content, bannerData.Variants, bannerData.LocalizedContent); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.BannerService.UpdateBanner(bannerID, expectedRevision, bannerData)
+	if err != nil {
 		if errors.Is(err, errs.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
+		} else if errors.Is(err, errs.ErrRevisionMismatch) {
+			h.revisionMismatch(w, bannerID)
+		} else if errors.Is(err, errs.ErrInvalidVariants) {
+			http.Error(w, ErrorResponse(InvalidVariantsMsg), http.StatusBadRequest)
+		} else if errors.Is(err, errs.ErrInvalidValue) {
+			// Новая граница вместе с сохранённой дают пустое окно
+			http.Error(w, ErrorResponse(InvalidActiveWindowMsg), http.StatusBadRequest)
 		} else if errors.Is(err, errs.ErrUniqueViolation) {
 			http.Error(w, ErrorResponse(ConflictMsg), http.StatusBadRequest)
 		} else {
@@ -318,9 +441,101 @@ func (h *UpdateBanner) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", revisionETag(revision))
 	w.WriteHeader(http.StatusOK)
 }
 
+// Ответ 412 с текущей ревизией, чтобы клиент мог перечитать баннер и предложить слияние правок
+func (h *UpdateBanner) revisionMismatch(w http.ResponseWriter, bannerID int64) {
+	type revisionMismatchBody struct {
+		Error    string `json:"error"`
+		Revision int64  `json:"revision"`
+	}
+
+	banner, err := h.BannerService.GetBannerByID(bannerID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", revisionETag(banner.Revision))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(revisionMismatchBody{Error: RevisionMismatchMsg, Revision: banner.Revision})
+}
+
+type GetBanner struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewGetBanner(bannerService BannerService, authService AuthService, logger logger.Logger) *GetBanner {
+	return &GetBanner{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *GetBanner) validate(idStr string) (int64, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, fmt.Errorf("validate bannerID: %w", err)
+	}
+
+	return id.Int64, nil
+}
+
+// Баннер целиком с ревизией в ETag, с которой админка затем отправляет PATCH
+func (h *GetBanner) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	bannerID, err := h.validate(
+		chi.URLParam(r, "bannerID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	banner, err := h.BannerService.GetBannerByID(bannerID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			h.logger.Error(err.Error())
+			http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	body, err := json.Marshal(banner)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONWithETag(w, r, body, revisionETag(banner.Revision), noCacheControl)
+}
+
 type DeleteBanner struct {
 	BannerService BannerService
 	AuthService   AuthService
@@ -366,7 +581,8 @@ func (h *DeleteBanner) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.BannerService.DeleteBanner(bannerID); err != nil {
+	jobID, err := h.BannerService.DeleteBanner(bannerID)
+	if err != nil {
 		if errors.Is(err, errs.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 		} else {
@@ -376,5 +592,75 @@ func (h *DeleteBanner) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ответ по API остаётся 204, статус удаления доступен по ссылке на задачу
+	w.Header().Set("Location", jobLocation(jobID))
 	w.WriteHeader(http.StatusNoContent)
 }
+
+type DeleteBanners struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewDeleteBanners(bannerService BannerService, authService AuthService, logger logger.Logger) *DeleteBanners {
+	return &DeleteBanners{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *DeleteBanners) validate(featureIDStr, tagIDStr string) (n.NullInt64, n.NullInt64, error) {
+	featureID, err := validateInt64(featureIDStr, false, n.NullInt64{})
+	if err != nil {
+		return n.NullInt64{}, n.NullInt64{}, fmt.Errorf("validate feature_id: %w", err)
+	}
+
+	tagID, err := validateInt64(tagIDStr, false, n.NullInt64{})
+	if err != nil {
+		return n.NullInt64{}, n.NullInt64{}, fmt.Errorf("validate tag_id: %w", err)
+	}
+
+	if !featureID.Valid && !tagID.Valid {
+		return n.NullInt64{}, n.NullInt64{}, fmt.Errorf("feature_id or tag_id is required: %w", errs.ErrRequiredValue)
+	}
+
+	return featureID, tagID, nil
+}
+
+func (h *DeleteBanners) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	featureID, tagID, err := h.validate(
+		r.URL.Query().Get("feature_id"),
+		r.URL.Query().Get("tag_id"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	jobID, err := h.BannerService.DeleteBanners(featureID, tagID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", jobLocation(jobID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]int64{"job_id": jobID})
+}
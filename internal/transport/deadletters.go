@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	n "backend-trainee-assignment-2024/internal/nullable"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ListDeadLetters struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewListDeadLetters(bannerService BannerService, authService AuthService, logger logger.Logger) *ListDeadLetters {
+	return &ListDeadLetters{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *ListDeadLetters) validate(limitStr, offsetStr string) (n.NullUint64, n.NullUint64, error) {
+	limit, err := validateUint64(limitStr, false, n.NullUint64{})
+	if err != nil {
+		return n.NullUint64{}, n.NullUint64{}, fmt.Errorf("validate limit: %w", err)
+	}
+	if limit.Valid && limit.Uint64 == 0 {
+		return n.NullUint64{}, n.NullUint64{}, fmt.Errorf("limit can't be 0: %w", errs.ErrInvalidValue)
+	}
+
+	offset, err := validateUint64(offsetStr, false, n.NullUint64{})
+	if err != nil {
+		return n.NullUint64{}, n.NullUint64{}, fmt.Errorf("validate offset: %w", err)
+	}
+
+	return limit, offset, nil
+}
+
+func (h *ListDeadLetters) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	limit, offset, err := h.validate(
+		r.URL.Query().Get("limit"),
+		r.URL.Query().Get("offset"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	deadLetters, err := h.BannerService.ListDeadLetters(limit, offset)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if len(deadLetters) > 0 {
+		json.NewEncoder(w).Encode(deadLetters)
+	}
+}
+
+type ReplayDeadLetter struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewReplayDeadLetter(bannerService BannerService, authService AuthService, logger logger.Logger) *ReplayDeadLetter {
+	return &ReplayDeadLetter{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *ReplayDeadLetter) validate(idStr string) (int64, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, fmt.Errorf("validate deadLetterID: %w", err)
+	}
+
+	return id.Int64, nil
+}
+
+func (h *ReplayDeadLetter) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	id, err := h.validate(
+		chi.URLParam(r, "deadLetterID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := h.BannerService.ReplayDeadLetter(id); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type DiscardDeadLetter struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewDiscardDeadLetter(bannerService BannerService, authService AuthService, logger logger.Logger) *DiscardDeadLetter {
+	return &DiscardDeadLetter{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *DiscardDeadLetter) validate(idStr string) (int64, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, fmt.Errorf("validate deadLetterID: %w", err)
+	}
+
+	return id.Int64, nil
+}
+
+func (h *DiscardDeadLetter) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	id, err := h.validate(
+		chi.URLParam(r, "deadLetterID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := h.BannerService.DiscardDeadLetter(id); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/logger"
+	"expvar"
+	"net/http"
+)
+
+// Счётчики процесса из expvar. Раскрывают внутреннее состояние сервиса, поэтому доступны только админам
+type DebugVars struct {
+	AuthService AuthService
+	logger      logger.Logger
+	vars        http.Handler
+}
+
+func NewDebugVars(authService AuthService, logger logger.Logger) *DebugVars {
+	return &DebugVars{
+		AuthService: authService,
+		logger:      logger,
+		vars:        expvar.Handler(),
+	}
+}
+
+func (h *DebugVars) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	h.vars.ServeHTTP(w, r)
+}
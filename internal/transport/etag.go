@@ -0,0 +1,75 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Сильный ETag по хешу тела ответа: совпадает, только если ответ совпадает побайтно
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Сравнение с If-None-Match. Для этого заголовка сравнение слабое, поэтому префикс W/ игнорируется
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func maxAgeCacheControl(maxAge time.Duration) string {
+	return fmt.Sprintf("max-age=%d", int(maxAge.Seconds()))
+}
+
+// Клиент может хранить ответ, но перед использованием обязан перепроверить его по ETag
+const noCacheControl = "no-cache"
+
+// ETag баннера для админов — его ревизия. По нему PATCH проверяет, что баннер не изменили с момента чтения
+func revisionETag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// Ревизия из If-Match. "*" означает любую ревизию, тогда проверка не выполняется
+func parseIfMatch(ifMatch string) (n.NullInt64, error) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "*" {
+		return n.NullInt64{}, nil
+	}
+
+	unquoted, err := strconv.Unquote(ifMatch)
+	if err != nil || !strings.HasPrefix(ifMatch, `"`) {
+		return n.NullInt64{}, errs.ErrInvalidValue
+	}
+	revision, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return n.NullInt64{}, errs.ErrInvalidValue
+	}
+	return n.NullInt64From(revision), nil
+}
+
+// Отправка JSON с ETag и Cache-Control. Если у клиента уже есть эта версия, отвечает 304 без тела
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, body []byte, etag, cacheControl string) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
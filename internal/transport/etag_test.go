@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := computeETag([]byte(`{"title":"some_title"}`))
+
+	testCases := []struct {
+		name        string
+		ifNoneMatch string
+		expected    bool
+	}{
+		{"Empty header", "", false},
+		{"Same tag", etag, true},
+		{"Weak same tag", "W/" + etag, true},
+		{"Tag in list", `"other", ` + etag, true},
+		{"Any tag", "*", true},
+		{"Other tag", `"other"`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, etagMatches(tc.ifNoneMatch, etag))
+		})
+	}
+}
+
+func TestWriteJSONWithETag(t *testing.T) {
+	body := []byte(`{"title":"some_title"}`)
+	cacheControl := maxAgeCacheControl(5 * time.Minute)
+
+	r := httptest.NewRequest(http.MethodGet, "/user_banner", nil)
+	w := httptest.NewRecorder()
+	writeJSONWithETag(w, r, body, computeETag(body), cacheControl)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, string(body), w.Body.String())
+	assert.Equal(t, "max-age=300", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	r = httptest.NewRequest(http.MethodGet, "/user_banner", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	writeJSONWithETag(w, r, body, computeETag(body), cacheControl)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, "max-age=300", w.Header().Get("Cache-Control"))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	testCases := []struct {
+		name        string
+		ifMatch     string
+		expected    n.NullInt64
+		expectedErr error
+	}{
+		{"Revision", revisionETag(3), n.NullInt64From(3), nil},
+		{"Any revision", "*", n.NullInt64{}, nil},
+		{"Empty header", "", n.NullInt64{}, errs.ErrInvalidValue},
+		{"Not quoted", "3", n.NullInt64{}, errs.ErrInvalidValue},
+		{"Weak tag", `W/"3"`, n.NullInt64{}, errs.ErrInvalidValue},
+		{"Not a revision", `"abc"`, n.NullInt64{}, errs.ErrInvalidValue},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			revision, err := parseIfMatch(tc.ifMatch)
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, revision)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// Ограничение на число событий в одном запросе POST /events
+	maxEvents = 100
+	// Статистика по умолчанию и максимум за столько дней
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+type TrackEvents struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewTrackEvents(bannerService BannerService, authService AuthService, logger logger.Logger) *TrackEvents {
+	return &TrackEvents{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+// Событие без варианта относится к основному содержимому баннера
+func (h *TrackEvents) validate(events []models.Event) error {
+	if len(events) == 0 {
+		return fmt.Errorf("validate events: %w", errs.ErrRequiredValue)
+	}
+	if len(events) > maxEvents {
+		return fmt.Errorf("validate events: at most %d events allowed: %w", maxEvents, errs.ErrInvalidValue)
+	}
+
+	for i := range events {
+		event := &events[i]
+		if event.Type != models.EventImpression && event.Type != models.EventClick {
+			return fmt.Errorf("validate type: %w", errs.ErrInvalidValue)
+		}
+		if event.BannerID <= 0 || event.TagID <= 0 {
+			return fmt.Errorf("validate banner_id and tag_id: %w", errs.ErrRequiredValue)
+		}
+		if event.Variant == "" {
+			event.Variant = models.DefaultVariantName
+		}
+		if len(event.Variant) > 64 {
+			return fmt.Errorf("validate variant: %w", errs.ErrInvalidValue)
+		}
+	}
+
+	return nil
+}
+
+func (h *TrackEvents) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	type eventsData struct {
+		Events []models.Event `json:"events"`
+	}
+
+	claims, err := h.AuthService.Claims(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	_, userID := claimsUser(claims)
+
+	var data eventsData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate(data.Events); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// События пишутся в базу асинхронно; если буфер переполнен, часть событий не принимается
+	accepted := h.BannerService.TrackEvents(userID, data.Events)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]int{"accepted": accepted})
+}
+
+type GetBannerStats struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewGetBannerStats(bannerService BannerService, authService AuthService, logger logger.Logger) *GetBannerStats {
+	return &GetBannerStats{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *GetBannerStats) validate(idStr, daysStr string) (int64, int, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("validate bannerID: %w", err)
+	}
+
+	days, err := validateUint64(daysStr, false, n.NullUint64{Valid: true, Uint64: defaultStatsDays})
+	if err != nil {
+		return 0, 0, fmt.Errorf("validate days: %w", err)
+	}
+	if days.Uint64 == 0 || days.Uint64 > maxStatsDays {
+		return 0, 0, fmt.Errorf("validate days: must be from 1 to %d: %w", maxStatsDays, errs.ErrInvalidValue)
+	}
+
+	return id.Int64, int(days.Uint64), nil
+}
+
+func (h *GetBannerStats) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	bannerID, days, err := h.validate(
+		chi.URLParam(r, "bannerID"),
+		r.URL.Query().Get("days"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	stats, err := h.BannerService.GetBannerStats(bannerID, days)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/services/auth"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Сервис с одним баннером и счётчиком показов в памяти. Остальные методы интерфейса не вызываются
+type cappedBannerService struct {
+	BannerService
+	banner      models.Banner
+	impressions map[int64]int64
+}
+
+func (s *cappedBannerService) GetBanner(tagID, featureID int64, useLastRevision bool, onlyActive bool) (models.Banner, bool, error) {
+	return s.banner, false, nil
+}
+
+func (s *cappedBannerService) CacheMaxAge(banner models.Banner) time.Duration {
+	return time.Minute
+}
+
+func (s *cappedBannerService) Localize(banner models.Banner, locales []string) (string, json.RawMessage) {
+	return "", banner.Content
+}
+
+func (s *cappedBannerService) AllowImpression(banner models.Banner, userID int64) bool {
+	s.impressions[userID]++
+	return s.impressions[userID] <= banner.FrequencyCap.Count
+}
+
+func (s *cappedBannerService) ImpressionCapped(banner models.Banner, userID int64) bool {
+	return s.impressions[userID] >= banner.FrequencyCap.Count
+}
+
+func TestGetBannerForUserFrequencyCap(t *testing.T) {
+	authService := auth.NewAuthService("secret_key")
+	token, err := authService.GenerateToken(7, UserRole)
+	require.NoError(t, err)
+
+	bannerService := &cappedBannerService{
+		banner: models.Banner{
+			ID: 1, Content: json.RawMessage(`{"title":"promo"}`), IsActive: true, FeatureID: 10, TagIds: []int64{100},
+			FrequencyCap: &models.FrequencyCap{Count: 2, Window: 86400},
+		},
+		impressions: make(map[int64]int64),
+	}
+	handler := NewGetBannerForUser(bannerService, authService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/user_banner?tag_id=100&feature_id=10", nil)
+		r.Header.Set("token", token)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.Handle(w, r)
+		return w
+	}
+
+	first := get("")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+
+	// Перепроверка до исчерпания ограничения не считается показом
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+	assert.Equal(t, int64(1), bannerService.impressions[7])
+
+	assert.Equal(t, http.StatusOK, get("").Code)
+
+	// После исчерпания ограничения копия из кэша клиента тоже не показывается
+	assert.Equal(t, http.StatusNotFound, get(etag).Code)
+	assert.Equal(t, http.StatusNotFound, get("*").Code)
+	assert.Equal(t, http.StatusNotFound, get("").Code)
+}
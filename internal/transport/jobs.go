@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	n "backend-trainee-assignment-2024/internal/nullable"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func jobLocation(jobID int64) string {
+	return fmt.Sprintf("/jobs/%d", jobID)
+}
+
+type GetJob struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewGetJob(bannerService BannerService, authService AuthService, logger logger.Logger) *GetJob {
+	return &GetJob{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *GetJob) validate(idStr string) (int64, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, fmt.Errorf("validate jobID: %w", err)
+	}
+
+	return id.Int64, nil
+}
+
+func (h *GetJob) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	jobID, err := h.validate(
+		chi.URLParam(r, "jobID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	job, err := h.BannerService.GetJob(jobID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
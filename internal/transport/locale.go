@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Больше языков из Accept-Language не рассматривается
+const maxRequestLocales = 10
+
+// Языки пользователя в порядке предпочтения. Параметр locale важнее заголовка Accept-Language
+func requestLocales(r *http.Request) []string {
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		return []string{strings.ToLower(locale)}
+	}
+	return parseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// Разбор Accept-Language с учётом весов q. Языки с q=0 и "*" пропускаются,
+// при равных весах сохраняется порядок из заголовка
+func parseAcceptLanguage(header string) []string {
+	type weightedLocale struct {
+		locale string
+		q      float64
+	}
+
+	var weighted []weightedLocale
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		locale := strings.ToLower(strings.TrimSpace(params[0]))
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			value, ok := strings.CutPrefix(strings.TrimSpace(param), "q=")
+			if !ok {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				parsed = 0
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		weighted = append(weighted, weightedLocale{locale: locale, q: q})
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].q > weighted[j].q
+	})
+	if len(weighted) > maxRequestLocales {
+		weighted = weighted[:maxRequestLocales]
+	}
+
+	locales := make([]string, 0, len(weighted))
+	for _, w := range weighted {
+		locales = append(locales, w.locale)
+	}
+	return locales
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBannerLocalize(t *testing.T) {
+	banner := models.Banner{
+		Content: json.RawMessage(`{"title":"base"}`),
+		LocalizedContent: models.LocalizedContent{
+			"en":    json.RawMessage(`{"title":"hello"}`),
+			"pt":    json.RawMessage(`{"title":"olá"}`),
+			"pt-br": json.RawMessage(`{"title":"oi"}`),
+		},
+	}
+
+	tests := []struct {
+		name            string
+		locales         []string
+		expectedLocale  string
+		expectedContent string
+	}{
+		{name: "exact match", locales: []string{"pt-br"}, expectedLocale: "pt-br", expectedContent: `{"title":"oi"}`},
+		{name: "case insensitive", locales: []string{"PT-BR"}, expectedLocale: "pt-br", expectedContent: `{"title":"oi"}`},
+		{name: "region falls back to language", locales: []string{"pt-pt"}, expectedLocale: "pt", expectedContent: `{"title":"olá"}`},
+		{name: "preference order", locales: []string{"de", "en", "pt"}, expectedLocale: "en", expectedContent: `{"title":"hello"}`},
+		{name: "no match", locales: []string{"de"}, expectedLocale: "", expectedContent: `{"title":"base"}`},
+		{name: "no locales", locales: nil, expectedLocale: "", expectedContent: `{"title":"base"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			locale, content := banner.Localize(test.locales)
+			assert.Equal(t, test.expectedLocale, locale)
+			assert.JSONEq(t, test.expectedContent, string(content))
+		})
+	}
+
+	t.Run("banner without translations", func(t *testing.T) {
+		locale, content := models.Banner{Content: banner.Content}.Localize([]string{"en"})
+		assert.Empty(t, locale)
+		assert.Equal(t, banner.Content, content)
+	})
+}
+
+func TestRequestLocales(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		expected       []string
+	}{
+		{name: "no locale", url: "/user_banner", expected: []string{}},
+		{name: "query parameter wins", url: "/user_banner?locale=DE", acceptLanguage: "en", expected: []string{"de"}},
+		{name: "single language", url: "/user_banner", acceptLanguage: "ru-RU", expected: []string{"ru-ru"}},
+		{name: "weights", url: "/user_banner", acceptLanguage: "en;q=0.5, ru-RU, ru;q=0.9, *;q=0.1", expected: []string{"ru-ru", "ru", "en"}},
+		{name: "zero and invalid weights are skipped", url: "/user_banner", acceptLanguage: "en;q=0, de;q=abc, fr", expected: []string{"fr"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.url, nil)
+			if test.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", test.acceptLanguage)
+			}
+			assert.Equal(t, test.expected, requestLocales(r))
+		})
+	}
+}
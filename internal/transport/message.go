@@ -1,10 +1,22 @@
 package transport
 
 const (
-	UnauthorizedMsg        = "Unauthorized"
-	ForbiddenMsg           = "Forbidden"
-	BadRequestMsg          = "Bad Request"
-	NotFoundMsg            = "Not Found"
-	ConflictMsg            = "Probably one of (tag_id, feature_id) is already exists"
-	InternalServerErrorMsg = "Internal Server Error"
+	UnauthorizedMsg            = "Unauthorized"
+	ForbiddenMsg               = "Forbidden"
+	BadRequestMsg              = "Bad Request"
+	NotFoundMsg                = "Not Found"
+	ConflictMsg                = "Probably one of (tag_id, feature_id) is already exists"
+	InternalServerErrorMsg     = "Internal Server Error"
+	PreconditionRequiredMsg    = "If-Match header with banner revision is required"
+	RevisionMismatchMsg        = "Banner was changed since it was read"
+	InvalidActiveWindowMsg     = "active_from must be before active_until"
+	InvalidVariantsMsg         = "variants must have unique non-empty names, content and non-negative weights"
+	InvalidFrequencyCapMsg     = "frequency_cap count and window must be both positive or both zero"
+	InvalidLocalizedContentMsg = "localized_content keys must be lowercase language tags and content cannot be null"
 )
+
+// Выставляется, если баннер отдан из последнего сохранённого состояния, пока база недоступна
+const DegradedHeader = "X-Data-Degraded"
+
+// Имя варианта баннера, который получил пользователь
+const VariantHeader = "X-Banner-Variant"
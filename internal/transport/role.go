@@ -4,3 +4,10 @@ const (
 	UserRole  = "user"
 	AdminRole = "admin"
 )
+
+// Тип и идентификатор пользователя из утверждений проверенного токена
+func claimsUser(claims map[string]any) (userType string, userID int64) {
+	userType, _ = claims["user_type"].(string)
+	id, _ := claims["user_id"].(float64)
+	return userType, int64(id)
+}
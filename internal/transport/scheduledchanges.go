@@ -0,0 +1,238 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateScheduledChange struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewCreateScheduledChange(bannerService BannerService, authService AuthService, logger logger.Logger) *CreateScheduledChange {
+	return &CreateScheduledChange{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *CreateScheduledChange) validate(idStr string) (int64, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, fmt.Errorf("validate bannerID: %w", err)
+	}
+
+	return id.Int64, nil
+}
+
+func (h *CreateScheduledChange) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	type scheduledChangeData struct {
+		ApplyAt models.UnixTime    `json:"apply_at"`
+		Change  models.BannerPatch `json:"change"`
+	}
+
+	bannerID, err := h.validate(
+		chi.URLParam(r, "bannerID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var data scheduledChangeData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if data.ApplyAt <= 0 {
+		http.Error(w, ErrorResponse("apply_at is required"), http.StatusBadRequest)
+		return
+	}
+	if data.Change.IsZero() {
+		http.Error(w, ErrorResponse("change cannot be empty"), http.StatusBadRequest)
+		return
+	}
+	if data.Change.ActiveWindow.Empty() {
+		http.Error(w, ErrorResponse(InvalidActiveWindowMsg), http.StatusBadRequest)
+		return
+	}
+	if validateVariants(data.Change.Variants) != nil {
+		http.Error(w, ErrorResponse(InvalidVariantsMsg), http.StatusBadRequest)
+		return
+	}
+	if data.Change.FrequencyCap != nil && !data.Change.FrequencyCap.Valid() {
+		http.Error(w, ErrorResponse(InvalidFrequencyCapMsg), http.StatusBadRequest)
+		return
+	}
+	if validateLocalizedContent(data.Change.LocalizedContent, true) != nil {
+		http.Error(w, ErrorResponse(InvalidLocalizedContentMsg), http.StatusBadRequest)
+		return
+	}
+	if err := validateTemplates(data.Change.Content, data.Change.Variants, data.Change.LocalizedContent); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.BannerService.ScheduleChange(bannerID, data.ApplyAt, data.Change)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"scheduled_change_id": id})
+}
+
+type ListScheduledChanges struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewListScheduledChanges(bannerService BannerService, authService AuthService, logger logger.Logger) *ListScheduledChanges {
+	return &ListScheduledChanges{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *ListScheduledChanges) validate(idStr string) (int64, error) {
+	id, err := validateInt64(idStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, fmt.Errorf("validate bannerID: %w", err)
+	}
+
+	return id.Int64, nil
+}
+
+func (h *ListScheduledChanges) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	bannerID, err := h.validate(
+		chi.URLParam(r, "bannerID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	changes, err := h.BannerService.ListScheduledChanges(bannerID)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changes)
+}
+
+type CancelScheduledChange struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewCancelScheduledChange(bannerService BannerService, authService AuthService, logger logger.Logger) *CancelScheduledChange {
+	return &CancelScheduledChange{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+func (h *CancelScheduledChange) validate(bannerIDStr, changeIDStr string) (int64, int64, error) {
+	bannerID, err := validateInt64(bannerIDStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("validate bannerID: %w", err)
+	}
+	changeID, err := validateInt64(changeIDStr, true, n.NullInt64{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("validate changeID: %w", err)
+	}
+
+	return bannerID.Int64, changeID.Int64, nil
+}
+
+func (h *CancelScheduledChange) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	bannerID, changeID, err := h.validate(
+		chi.URLParam(r, "bannerID"),
+		chi.URLParam(r, "changeID"),
+	)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	userType, err := h.AuthService.ValidateToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if userType != AdminRole {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Уже применённое или отменённое изменение отменить нельзя
+	err = h.BannerService.CancelScheduledChange(bannerID, changeID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -18,16 +18,33 @@ import (
 
 type AuthService interface {
 	ValidateToken(token string) (userType string, err error)
+	Claims(token string) (claims map[string]any, err error)
 }
 
 type BannerService interface {
-	GetBanner(tagID, featureID int64, useLastRevision bool, onlyActive bool) (models.Banner, error)
+	GetBanner(tagID, featureID int64, useLastRevision bool, onlyActive bool) (models.Banner, bool, error)
+	GetBanners(keys []models.BannerKey, onlyActive bool) ([]models.BannerLookup, bool, error)
 	ListBanners(featureID, tagID n.NullInt64, limit, offset n.NullUint64) ([]models.Banner, error)
-	CreateBanner(tagIDs []int64, featureID int64, content json.RawMessage, isActive bool) (int64, error)
-	UpdateBanner(bannerID int64, tagIDs []int64, featureID n.NullInt64, content json.RawMessage, isActive n.NullBool) error
-	DeleteBanner(bannerID int64) error
+	CreateBanner(input models.BannerInput) (int64, error)
+	GetBannerByID(bannerID int64) (models.Banner, error)
+	UpdateBanner(bannerID int64, expectedRevision n.NullInt64, patch models.BannerPatch) (int64, error)
+	DeleteBanner(bannerID int64) (int64, error)
+	DeleteBanners(featureID, tagID n.NullInt64) (int64, error)
+	GetJob(jobID int64) (models.Job, error)
+	ListDeadLetters(limit, offset n.NullUint64) ([]models.DeadLetter, error)
+	ReplayDeadLetter(id int64) error
+	DiscardDeadLetter(id int64) error
 	ListBannerVersions(bannerID int64) ([]models.BannerVersion, error)
 	RestoreVersion(bannerID int64, updatedAt models.UnixTime) error
+	ScheduleChange(bannerID int64, applyAt models.UnixTime, change models.BannerPatch) (int64, error)
+	ListScheduledChanges(bannerID int64) ([]models.ScheduledChange, error)
+	CancelScheduledChange(bannerID, changeID int64) error
+	TrackEvents(userID int64, events []models.Event) int
+	GetBannerStats(bannerID int64, days int) ([]models.DailyStats, error)
+	CacheMaxAge(banner models.Banner) time.Duration
+	AllowImpression(banner models.Banner, userID int64) bool
+	ImpressionCapped(banner models.Banner, userID int64) bool
+	Localize(banner models.Banner, locales []string) (string, json.RawMessage)
 }
 
 type HTTPServer struct {
@@ -93,26 +110,64 @@ func (s *HTTPServer) getRouter() *chi.Mux {
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	getBannerForUser := NewGetBannerForUser(s.bannerService, s.authService, s.logger)
+	getBannersForUser := NewGetBannersForUser(s.bannerService, s.authService, s.logger)
 	listBanners := NewListBanners(s.bannerService, s.authService, s.logger)
 	createBanner := NewCreateBanner(s.bannerService, s.authService, s.logger)
+	getBanner := NewGetBanner(s.bannerService, s.authService, s.logger)
 	updateBanner := NewUpdateBanner(s.bannerService, s.authService, s.logger)
 	deleteBanner := NewDeleteBanner(s.bannerService, s.authService, s.logger)
+	deleteBanners := NewDeleteBanners(s.bannerService, s.authService, s.logger)
 	listVersions := NewListVersions(s.bannerService, s.authService, s.logger)
 	restoreVersion := NewRestoreVersion(s.bannerService, s.authService, s.logger)
+	createScheduledChange := NewCreateScheduledChange(s.bannerService, s.authService, s.logger)
+	listScheduledChanges := NewListScheduledChanges(s.bannerService, s.authService, s.logger)
+	cancelScheduledChange := NewCancelScheduledChange(s.bannerService, s.authService, s.logger)
+	trackEvents := NewTrackEvents(s.bannerService, s.authService, s.logger)
+	getBannerStats := NewGetBannerStats(s.bannerService, s.authService, s.logger)
+	getJob := NewGetJob(s.bannerService, s.authService, s.logger)
+	listDeadLetters := NewListDeadLetters(s.bannerService, s.authService, s.logger)
+	replayDeadLetter := NewReplayDeadLetter(s.bannerService, s.authService, s.logger)
+	discardDeadLetter := NewDiscardDeadLetter(s.bannerService, s.authService, s.logger)
+	debugVars := NewDebugVars(s.authService, s.logger)
 
 	r.Get("/user_banner", getBannerForUser.Handle)
+	r.Post("/user_banners", getBannersForUser.Handle) // POST /user_banners
+	r.Post("/events", trackEvents.Handle)             // POST /events
 	r.Route("/banner", func(r chi.Router) {
-		r.Get("/", listBanners.Handle)   // GET /banner
-		r.Post("/", createBanner.Handle) // POST /banner
+		r.Get("/", listBanners.Handle)      // GET /banner
+		r.Post("/", createBanner.Handle)    // POST /banner
+		r.Delete("/", deleteBanners.Handle) // DELETE /banner?feature_id=X&tag_id=Y
 
 		r.Route("/{bannerID}", func(r chi.Router) {
+			r.Get("/", getBanner.Handle)       // GET /banner/{bannerID}
 			r.Patch("/", updateBanner.Handle)  // PATCH /banner/{bannerID}
 			r.Delete("/", deleteBanner.Handle) // DELETE /banner/{bannerID}
 
+			r.Get("/stats", getBannerStats.Handle) // GET /banner/{bannerID}/stats?days=N
+
 			r.Route("/versions", func(r chi.Router) {
 				r.Get("/", listVersions.Handle)                       // GET /banner/{bannerID}/versions
 				r.Post("/{updatedAt}/restore", restoreVersion.Handle) // POST /banner/{bannerID}/versions/{versionID}/restore
 			})
+
+			r.Route("/scheduled_changes", func(r chi.Router) {
+				r.Get("/", listScheduledChanges.Handle)               // GET /banner/{bannerID}/scheduled_changes
+				r.Post("/", createScheduledChange.Handle)             // POST /banner/{bannerID}/scheduled_changes
+				r.Delete("/{changeID}", cancelScheduledChange.Handle) // DELETE /banner/{bannerID}/scheduled_changes/{changeID}
+			})
+		})
+	})
+
+	r.Get("/jobs/{jobID}", getJob.Handle) // GET /jobs/{jobID}
+
+	r.Get("/debug/vars", debugVars.Handle) // GET /debug/vars
+
+	r.Route("/dead_letters", func(r chi.Router) {
+		r.Get("/", listDeadLetters.Handle) // GET /dead_letters
+
+		r.Route("/{deadLetterID}", func(r chi.Router) {
+			r.Post("/replay", replayDeadLetter.Handle) // POST /dead_letters/{deadLetterID}/replay
+			r.Delete("/", discardDeadLetter.Handle)    // DELETE /dead_letters/{deadLetterID}
 		})
 	})
 
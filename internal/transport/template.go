@@ -0,0 +1,21 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Заполнение плейсхолдеров содержимого значениями из токена, параметров запроса и сервера
+func renderContent(claims map[string]any, r *http.Request, content json.RawMessage) (json.RawMessage, error) {
+	if !models.HasTemplate(content) {
+		return content, nil
+	}
+
+	return models.RenderTemplate(content, models.TemplateData{
+		Claims: claims,
+		Query:  r.URL.Query(),
+		Now:    time.Now(),
+	})
+}
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := models.TemplateData{
+		Claims: map[string]any{"user_id": float64(7), "name": "Анна", "meta": map[string]any{"a": 1}},
+		Query:  url.Values{"city": {`Москва "центр"`}, "html": {`<script>alert(1)</script>`}},
+		Now:    time.Unix(1712000000, 0),
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "no placeholders", content: `{"title": "sale", "price": 100}`, expected: `{"title": "sale", "price": 100}`},
+		{name: "claims", content: `{"title": "Привет, {{claims.name}}! #{{claims.user_id}}"}`, expected: `{"title": "Привет, Анна! #7"}`},
+		{name: "query is escaped", content: `{"city": "{{ query.city }}"}`, expected: `{"city": "Москва \"центр\""}`},
+		{name: "default value", content: `{"title": "{{query.name|друг}}, {{claims.meta|}}!"}`, expected: `{"title": "друг, !"}`},
+		{name: "server values", content: `{"now": "{{server.now}}", "date": "{{server.date}}"}`, expected: `{"now": "1712000000", "date": "2024-04-01"}`},
+		{name: "countdown", content: `{"left": "{{server.countdown.1712000090}}", "over": "{{server.countdown.1}}"}`, expected: `{"left": "90", "over": "0"}`},
+		{name: "nested values and numbers", content: `{"items": [{"text": "{{claims.user_id}}"}], "big": 12345678901234567890}`, expected: `{"items": [{"text": "7"}], "big": 12345678901234567890}`},
+		{name: "legacy broken placeholder kept", content: `{"title": "{{oops"}`, expected: `{"title": "{{oops"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered, err := models.RenderTemplate(json.RawMessage(test.content), data)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(rendered))
+		})
+	}
+
+	t.Run("html is escaped", func(t *testing.T) {
+		rendered, err := models.RenderTemplate(json.RawMessage(`{"title": "{{query.html}}"}`), data)
+		require.NoError(t, err)
+		assert.NotContains(t, string(rendered), "<script>")
+		assert.JSONEq(t, `{"title": "<script>alert(1)</script>"}`, string(rendered))
+	})
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{name: "no placeholders", content: `{"title": "sale"}`, valid: true},
+		{name: "valid placeholders", content: `{"title": "{{claims.name|друг}} {{query.utm-source}} {{server.countdown.1712000000}}"}`, valid: true},
+		{name: "unclosed", content: `{"title": "{{claims.name"}`},
+		{name: "unknown source", content: `{"title": "{{env.SECRET}}"}`},
+		{name: "missing name", content: `{"title": "{{claims}}"}`},
+		{name: "empty", content: `{"title": "{{}}"}`},
+		{name: "unknown server value", content: `{"title": "{{server.hostname}}"}`},
+		{name: "bad countdown", content: `{"title": "{{server.countdown.tomorrow}}"}`},
+		{name: "nested", content: `{"items": ["ok", {"text": "{{query.}}"}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := models.ValidateTemplate(json.RawMessage(test.content))
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errs.ErrInvalidPlaceholder)
+			}
+		})
+	}
+}
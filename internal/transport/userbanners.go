@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/logger"
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Ограничение на число пар в одном запросе POST /user_banners
+const maxUserBannerPairs = 100
+
+type GetBannersForUser struct {
+	BannerService BannerService
+	AuthService   AuthService
+	logger        logger.Logger
+}
+
+func NewGetBannersForUser(bannerService BannerService, authService AuthService, logger logger.Logger) *GetBannersForUser {
+	return &GetBannersForUser{
+		BannerService: bannerService,
+		AuthService:   authService,
+		logger:        logger,
+	}
+}
+
+// Пары передаются либо списком pairs, либо одним тегом tag_id со списком фич feature_ids
+type userBannersRequest struct {
+	Pairs      []models.BannerKey `json:"pairs"`
+	TagID      *int64             `json:"tag_id"`
+	FeatureIDs []int64            `json:"feature_ids"`
+}
+
+type userBannerResult struct {
+	Status  models.BannerStatus `json:"status"`
+	Content json.RawMessage     `json:"content,omitempty"`
+	Variant string              `json:"variant,omitempty"`
+	Locale  string              `json:"locale,omitempty"`
+}
+
+func (h *GetBannersForUser) validate(req userBannersRequest) ([]models.BannerKey, error) {
+	if req.TagID != nil && len(req.Pairs) > 0 {
+		return nil, fmt.Errorf("validate pairs: pairs and tag_id are mutually exclusive: %w", errs.ErrInvalidValue)
+	}
+
+	keys := req.Pairs
+	if req.TagID != nil {
+		if len(req.FeatureIDs) == 0 {
+			return nil, fmt.Errorf("validate feature_ids: %w", errs.ErrRequiredValue)
+		}
+		keys = make([]models.BannerKey, 0, len(req.FeatureIDs))
+		for _, featureID := range req.FeatureIDs {
+			keys = append(keys, models.BannerKey{TagID: *req.TagID, FeatureID: featureID})
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("validate pairs: %w", errs.ErrRequiredValue)
+	}
+	if len(keys) > maxUserBannerPairs {
+		return nil, fmt.Errorf("validate pairs: at most %d pairs allowed: %w", maxUserBannerPairs, errs.ErrInvalidValue)
+	}
+
+	return keys, nil
+}
+
+func (h *GetBannersForUser) Handle(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+
+	claims, err := h.AuthService.Claims(token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	userType, userID := claimsUser(claims)
+
+	var req userBannersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.validate(req)
+	if err != nil {
+		http.Error(w, ErrorResponse(err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	onlyActive := userType == UserRole
+	locales := requestLocales(r)
+
+	lookups, degraded, err := h.BannerService.GetBanners(keys, onlyActive)
+	if err != nil {
+		h.logger.Error(err.Error())
+		http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+		return
+	}
+
+	// Ответ — объект с ключами вида "tagID_featureID"
+	results := make(map[string]userBannerResult, len(lookups))
+	for _, lookup := range lookups {
+		result := userBannerResult{Status: lookup.Status}
+		// Исчерпанное ограничение частоты показов выглядит так же, как в /user_banner
+		if lookup.Status == models.BannerFound && onlyActive && !h.BannerService.AllowImpression(lookup.Banner, userID) {
+			result.Status = models.BannerNotFound
+		}
+		if result.Status == models.BannerFound {
+			banner := lookup.Banner
+			locale, localized := h.BannerService.Localize(banner, locales)
+			banner.Content = localized
+			result.Variant, result.Content = banner.PickVariant(userID)
+			if result.Variant == models.DefaultVariantName {
+				result.Locale = locale
+			}
+			result.Content, err = renderContent(claims, r, result.Content)
+			if err != nil {
+				h.logger.Error(err.Error())
+				http.Error(w, ErrorResponse(InternalServerErrorMsg), http.StatusInternalServerError)
+				return
+			}
+		}
+		results[fmt.Sprintf("%d_%d", lookup.Key.TagID, lookup.Key.FeatureID)] = result
+	}
+
+	if degraded {
+		w.Header().Set(DegradedHeader, "true")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
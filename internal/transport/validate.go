@@ -2,7 +2,10 @@ package transport
 
 import (
 	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
 	n "backend-trainee-assignment-2024/internal/nullable"
+	"encoding/json"
+	"fmt"
 	"strconv"
 )
 
@@ -57,3 +60,66 @@ func validateBool(value string, required bool, defaultValue n.NullBool) (n.NullB
 	}
 	return n.NullBool{Valid: true, Bool: res}, nil
 }
+
+// Имена вариантов непустые и не повторяются, имя основного содержимого занято
+func validateVariants(variants []models.BannerVariant) error {
+	names := make(map[string]struct{}, len(variants))
+	for _, variant := range variants {
+		if variant.Name == "" || variant.Name == models.DefaultVariantName || len(variant.Name) > 64 {
+			return errs.ErrInvalidValue
+		}
+		if _, ok := names[variant.Name]; ok {
+			return errs.ErrInvalidValue
+		}
+		names[variant.Name] = struct{}{}
+
+		if variant.Content == nil || string(variant.Content) == "null" || variant.Weight < 0 {
+			return errs.ErrInvalidValue
+		}
+	}
+	return nil
+}
+
+// Языки — теги из латинских букв, цифр и дефисов в нижнем регистре.
+// При создании перевод не может быть null, в PATCH null удаляет перевод
+func validateLocalizedContent(localizedContent models.LocalizedContent, allowNull bool) error {
+	for locale, content := range localizedContent {
+		if !validLocale(locale) {
+			return errs.ErrInvalidValue
+		}
+		if !allowNull && (content == nil || string(content) == "null") {
+			return errs.ErrInvalidValue
+		}
+	}
+	return nil
+}
+
+func validLocale(locale string) bool {
+	if locale == "" || len(locale) > 35 || locale[0] == '-' || locale[len(locale)-1] == '-' {
+		return false
+	}
+	for _, c := range locale {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Плейсхолдеры проверяются во всём содержимом баннера: основном, вариантах и переводах
+func validateTemplates(content json.RawMessage, variants []models.BannerVariant, localizedContent models.LocalizedContent) error {
+	if err := models.ValidateTemplate(content); err != nil {
+		return fmt.Errorf("validate content: %w", err)
+	}
+	for _, variant := range variants {
+		if err := models.ValidateTemplate(variant.Content); err != nil {
+			return fmt.Errorf("validate variant %q: %w", variant.Name, err)
+		}
+	}
+	for locale, localized := range localizedContent {
+		if err := models.ValidateTemplate(localized); err != nil {
+			return fmt.Errorf("validate localized_content %q: %w", locale, err)
+		}
+	}
+	return nil
+}
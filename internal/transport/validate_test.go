@@ -2,7 +2,10 @@ package transport
 
 import (
 	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
 	n "backend-trainee-assignment-2024/internal/nullable"
+	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -130,3 +133,88 @@ func TestValidateBool(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateVariants(t *testing.T) {
+	content := json.RawMessage(`{"title":"a"}`)
+
+	testCases := []struct {
+		name        string
+		variants    []models.BannerVariant
+		expectedErr error
+	}{
+		{"No variants", nil, nil},
+		{"Valid variants", []models.BannerVariant{{Name: "a", Content: content, Weight: 1}, {Name: "b", Content: content, Weight: 0}}, nil},
+		{"Empty name", []models.BannerVariant{{Name: "", Content: content, Weight: 1}}, errs.ErrInvalidValue},
+		{"Reserved name", []models.BannerVariant{{Name: models.DefaultVariantName, Content: content, Weight: 1}}, errs.ErrInvalidValue},
+		{"Duplicate name", []models.BannerVariant{{Name: "a", Content: content, Weight: 1}, {Name: "a", Content: content, Weight: 1}}, errs.ErrInvalidValue},
+		{"Missing content", []models.BannerVariant{{Name: "a", Content: json.RawMessage(`null`), Weight: 1}}, errs.ErrInvalidValue},
+		{"Negative weight", []models.BannerVariant{{Name: "a", Content: content, Weight: -1}}, errs.ErrInvalidValue},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateVariants(tc.variants)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateLocalizedContent(t *testing.T) {
+	content := json.RawMessage(`{"title":"a"}`)
+
+	testCases := []struct {
+		name             string
+		localizedContent models.LocalizedContent
+		allowNull        bool
+		expectedErr      error
+	}{
+		{"No translations", nil, false, nil},
+		{"Valid locales", models.LocalizedContent{"en": content, "pt-br": content, "es-419": content}, false, nil},
+		{"Empty locale", models.LocalizedContent{"": content}, false, errs.ErrInvalidValue},
+		{"Uppercase locale", models.LocalizedContent{"EN": content}, false, errs.ErrInvalidValue},
+		{"Leading hyphen", models.LocalizedContent{"-en": content}, false, errs.ErrInvalidValue},
+		{"Underscore", models.LocalizedContent{"pt_br": content}, false, errs.ErrInvalidValue},
+		{"Null on create", models.LocalizedContent{"en": json.RawMessage(`null`)}, false, errs.ErrInvalidValue},
+		{"Null on update", models.LocalizedContent{"en": json.RawMessage(`null`)}, true, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateLocalizedContent(tc.localizedContent, tc.allowNull)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	valid := json.RawMessage(`{"title":"{{claims.name}}"}`)
+	broken := json.RawMessage(`{"title":"{{claims.name"}`)
+
+	testCases := []struct {
+		name             string
+		content          json.RawMessage
+		variants         []models.BannerVariant
+		localizedContent models.LocalizedContent
+		expectedErr      error
+	}{
+		{"No content", nil, nil, nil, nil},
+		{"Valid templates", valid, []models.BannerVariant{{Name: "a", Content: valid}}, models.LocalizedContent{"en": valid}, nil},
+		{"Broken content", broken, nil, nil, errs.ErrInvalidPlaceholder},
+		{"Broken variant", valid, []models.BannerVariant{{Name: "a", Content: broken}}, nil, errs.ErrInvalidPlaceholder},
+		{"Broken translation", nil, nil, models.LocalizedContent{"en": broken}, errs.ErrInvalidPlaceholder},
+		{"Removed translation", nil, nil, models.LocalizedContent{"en": json.RawMessage(`null`)}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTemplates(tc.content, tc.variants, tc.localizedContent)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBannerPickVariant(t *testing.T) {
+	banner := models.Banner{
+		ID:      1,
+		Content: json.RawMessage(`{"title":"base"}`),
+	}
+
+	t.Run("no variants", func(t *testing.T) {
+		name, content := banner.PickVariant(42)
+		assert.Equal(t, models.DefaultVariantName, name)
+		assert.Equal(t, banner.Content, content)
+	})
+
+	banner.Variants = []models.BannerVariant{
+		{Name: "a", Content: json.RawMessage(`{"title":"a"}`), Weight: 1},
+		{Name: "b", Content: json.RawMessage(`{"title":"b"}`), Weight: 3},
+		{Name: "off", Content: json.RawMessage(`{"title":"off"}`), Weight: 0},
+	}
+
+	t.Run("sticky", func(t *testing.T) {
+		name, _ := banner.PickVariant(42)
+		for i := 0; i < 10; i++ {
+			next, _ := banner.PickVariant(42)
+			assert.Equal(t, name, next)
+		}
+
+		// Порядок вариантов не влияет на выбор
+		reordered := banner
+		reordered.Variants = []models.BannerVariant{banner.Variants[2], banner.Variants[1], banner.Variants[0]}
+		next, _ := reordered.PickVariant(42)
+		assert.Equal(t, name, next)
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		counts := make(map[string]int)
+		for userID := int64(0); userID < 4000; userID++ {
+			name, _ := banner.PickVariant(userID)
+			counts[name]++
+		}
+		assert.Zero(t, counts["off"])
+		assert.InDelta(t, 1000, counts["a"], 150)
+		assert.InDelta(t, 3000, counts["b"], 150)
+	})
+}
@@ -64,7 +64,11 @@ func (s *E2ESuite) SetupSuite() {
 
 	// Инициализация репозиториев
 	logger.Info("initializing repos...")
-	repo := repo.NewBannerRepository(postgres)
+	bannerRepo := repo.NewBannerRepository(postgres)
+	jobRepo := repo.NewJobRepository(postgres)
+	deadLetterRepo := repo.NewDeadLetterRepository(postgres)
+	scheduledChangeRepo := repo.NewScheduledChangeRepository(postgres)
+	eventRepo := repo.NewEventRepository(postgres)
 
 	cache := redis.NewRedis(cfg.Redis)
 	s.cache = cache
@@ -77,7 +81,7 @@ func (s *E2ESuite) SetupSuite() {
 	}
 
 	// Инициализация сервисов
-	bannerService := banner.NewBannerService(cfg.BannerService, repo, cache, queue, logger)
+	bannerService := banner.NewBannerService(cfg.BannerService, bannerRepo, jobRepo, deadLetterRepo, scheduledChangeRepo, eventRepo, cache, queue, logger)
 	s.bannerService = bannerService
 	authService := auth.NewAuthService(cfg.AuthService.SecretKey)
 	s.authService = authService
@@ -383,6 +387,7 @@ func (s *E2ESuite) Test3_UpdateBanner() {
 		name         string
 		url          string
 		token        string
+		ifMatch      string
 		payload      string
 		expectedCode int
 	}{
@@ -390,13 +395,31 @@ func (s *E2ESuite) Test3_UpdateBanner() {
 			name:         "successful update banner",
 			url:          "/banner/1",
 			token:        adminToken,
+			ifMatch:      `"1"`,
 			payload:      `{"tag_ids": [345346346], "feature_id": 345555, "content": {"titlenew": "some_title_updated", "text": "some_text", "url": "some_url_updated"}, "is_active": true}`,
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:         "stale revision",
+			url:          "/banner/1",
+			token:        adminToken,
+			ifMatch:      `"1"`,
+			payload:      `{"is_active": false}`,
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "missing If-Match",
+			url:          "/banner/1",
+			token:        adminToken,
+			ifMatch:      "",
+			payload:      `{"is_active": false}`,
+			expectedCode: http.StatusPreconditionRequired,
+		},
 		{
 			name:         "invalid tag_id",
 			url:          "/banner/1",
 			token:        adminToken,
+			ifMatch:      `"*"`,
 			payload:      `{"tag_ids": ["abc"], "feature_id": 1001, "content": {"title": "some_title_updated", "text": "some_text", "url": "some_url_updated"}, "is_active": true}`,
 			expectedCode: http.StatusBadRequest,
 		},
@@ -404,6 +427,7 @@ func (s *E2ESuite) Test3_UpdateBanner() {
 			name:         "banner not found",
 			url:          "/banner/100555555",
 			token:        adminToken,
+			ifMatch:      `"*"`,
 			payload:      `{"tag_ids": [2001], "feature_id": 1001, "content": {"title": "some_title_updated", "text": "some_text", "url": "some_url_updated"}, "is_active": true}`,
 			expectedCode: http.StatusNotFound,
 		},
@@ -411,6 +435,7 @@ func (s *E2ESuite) Test3_UpdateBanner() {
 			name:         "unauthorized",
 			url:          "/banner/1",
 			token:        "",
+			ifMatch:      `"*"`,
 			payload:      `{"tag_ids": [2001], "feature_id": 1001, "content": {"title": "some_title_updated", "text": "some_text", "url": "some_url_updated"}, "is_active": true}`,
 			expectedCode: http.StatusUnauthorized,
 		},
@@ -418,6 +443,7 @@ func (s *E2ESuite) Test3_UpdateBanner() {
 			name:         "forbidden",
 			url:          "/banner/1",
 			token:        userToken,
+			ifMatch:      `"*"`,
 			payload:      `{"tag_ids": [2001], "feature_id": 1001, "content": {"title": "some_title_updated", "text": "some_text", "url": "some_url_updated"}, "is_active": true}`,
 			expectedCode: http.StatusForbidden,
 		},
@@ -433,6 +459,9 @@ func (s *E2ESuite) Test3_UpdateBanner() {
 
 			req.Header.Set("token", test.token)
 			req.Header.Set("Content-Type", "application/json")
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
 
 			cli := http.Client{}
 
@@ -665,3 +694,241 @@ func (s *E2ESuite) Test6_RestoreVersion() {
 		})
 	}
 }
+
+func (s *E2ESuite) TestDeleteBanners() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	tests := []struct {
+		name         string
+		url          string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "successful delete banners by feature",
+			url:          "/banner?feature_id=1003",
+			token:        adminToken,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "successful delete banners by tag",
+			url:          "/banner?tag_id=2005",
+			token:        adminToken,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "filter is required",
+			url:          "/banner",
+			token:        adminToken,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"feature_id or tag_id is required: value is required"}`,
+		},
+		{
+			name:         "invalid feature_id",
+			url:          "/banner?feature_id=abc",
+			token:        adminToken,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"validate feature_id: invalid value"}`,
+		},
+		{
+			name:         "unauthorized",
+			url:          "/banner?feature_id=1003",
+			token:        "",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "forbidden",
+			url:          "/banner?feature_id=1003",
+			token:        userToken,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			fullAddr := fmt.Sprintf("%s:%d", s.server.Address, s.server.Port)
+			path := "http://" + fullAddr + test.url
+
+			req, err := http.NewRequest("DELETE", path, nil)
+			assert.NoError(s.T(), err)
+
+			req.Header.Set("token", test.token)
+
+			cli := http.Client{}
+
+			res, err := cli.Do(req)
+			assert.NoError(s.T(), err)
+			defer res.Body.Close()
+
+			assert.Equal(s.T(), test.expectedCode, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			assert.NoError(s.T(), err)
+
+			if test.expectedBody != "" {
+				assert.JSONEq(s.T(), test.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func (s *E2ESuite) TestGetJob() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	fullAddr := fmt.Sprintf("%s:%d", s.server.Address, s.server.Port)
+	cli := http.Client{}
+
+	// Постановка удаления в очередь
+	req, err := http.NewRequest("DELETE", "http://"+fullAddr+"/banner?feature_id=1007", nil)
+	assert.NoError(s.T(), err)
+	req.Header.Set("token", adminToken)
+
+	res, err := cli.Do(req)
+	if err != nil {
+		s.T().Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+	assert.Equal(s.T(), http.StatusAccepted, res.StatusCode)
+
+	var body struct {
+		JobID int64 `json:"job_id"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), fmt.Sprintf("/jobs/%d", body.JobID), res.Header.Get("Location"))
+
+	getJob := func(url, token string) (int, models.Job) {
+		req, err := http.NewRequest("GET", "http://"+fullAddr+url, nil)
+		assert.NoError(s.T(), err)
+		req.Header.Set("token", token)
+
+		res, err := cli.Do(req)
+		if err != nil {
+			s.T().Fatalf("request failed: %v", err)
+		}
+		defer res.Body.Close()
+
+		var job models.Job
+		if res.StatusCode == http.StatusOK {
+			assert.NoError(s.T(), json.NewDecoder(res.Body).Decode(&job))
+		}
+		return res.StatusCode, job
+	}
+
+	s.Run("job eventually done", func() {
+		url := fmt.Sprintf("/jobs/%d", body.JobID)
+		var job models.Job
+		for i := 0; i < 50; i++ {
+			var code int
+			code, job = getJob(url, adminToken)
+			assert.Equal(s.T(), http.StatusOK, code)
+			if job.Status == models.JobDone || job.Status == models.JobFailed {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		assert.Equal(s.T(), models.JobDone, job.Status)
+	})
+
+	s.Run("job not found", func() {
+		code, _ := getJob(fmt.Sprintf("/jobs/%d", body.JobID+1000), adminToken)
+		assert.Equal(s.T(), http.StatusNotFound, code)
+	})
+
+	s.Run("invalid job ID", func() {
+		code, _ := getJob("/jobs/abc", adminToken)
+		assert.Equal(s.T(), http.StatusBadRequest, code)
+	})
+
+	s.Run("forbidden", func() {
+		code, _ := getJob(fmt.Sprintf("/jobs/%d", body.JobID), userToken)
+		assert.Equal(s.T(), http.StatusForbidden, code)
+	})
+}
+
+func (s *E2ESuite) TestDeadLetters() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		token        string
+		expectedCode int
+	}{
+		{
+			name:         "successful list dead letters",
+			method:       "GET",
+			url:          "/dead_letters?limit=10",
+			token:        adminToken,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid limit",
+			method:       "GET",
+			url:          "/dead_letters?limit=abc",
+			token:        adminToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "forbidden list dead letters",
+			method:       "GET",
+			url:          "/dead_letters",
+			token:        userToken,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "replay not found",
+			method:       "POST",
+			url:          "/dead_letters/100500/replay",
+			token:        adminToken,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "discard not found",
+			method:       "DELETE",
+			url:          "/dead_letters/100500",
+			token:        adminToken,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid dead letter ID",
+			method:       "DELETE",
+			url:          "/dead_letters/abc",
+			token:        adminToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unauthorized",
+			method:       "POST",
+			url:          "/dead_letters/1/replay",
+			token:        "",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			fullAddr := fmt.Sprintf("%s:%d", s.server.Address, s.server.Port)
+			path := "http://" + fullAddr + test.url
+
+			req, err := http.NewRequest(test.method, path, nil)
+			assert.NoError(s.T(), err)
+
+			req.Header.Set("token", test.token)
+
+			cli := http.Client{}
+			res, err := cli.Do(req)
+			if err != nil {
+				s.T().Fatalf("request failed: %v", err)
+			}
+			defer res.Body.Close()
+
+			assert.Equal(s.T(), test.expectedCode, res.StatusCode)
+		})
+	}
+}
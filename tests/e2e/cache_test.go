@@ -0,0 +1,178 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) request(method, url, token, payload string) (int, string) {
+	code, body, _ := s.requestWithHeaders(method, url, token, payload, nil)
+	return code, body
+}
+
+func (s *E2ESuite) requestWithHeaders(method, url, token, payload string, headers map[string]string) (int, string, http.Header) {
+	fullAddr := fmt.Sprintf("%s:%d", s.server.Address, s.server.Port)
+
+	req, err := http.NewRequest(method, "http://"+fullAddr+url, strings.NewReader(payload))
+	require.NoError(s.T(), err)
+	req.Header.Set("token", token)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	cli := http.Client{}
+	res, err := cli.Do(req)
+	require.NoError(s.T(), err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(s.T(), err)
+
+	return res.StatusCode, string(body), res.Header
+}
+
+func (s *E2ESuite) TestBannerCache() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	code, body := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9101, 9102], "feature_id": 9100, "content": {"title": "cached"}, "is_active": true}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+
+	code, body = s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9201], "feature_id": 9200, "content": {"title": "disabled"}, "is_active": false}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	s.Run("cache is filled under every tag of the banner", func() {
+		code, body := s.request("GET", "/user_banner?tag_id=9101&feature_id=9100", userToken, "")
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "cached"}`, body)
+
+		for _, key := range []string{"9101_9100", "9102_9100"} {
+			_, err := s.cache.Get(key)
+			assert.NoError(s.T(), err, key)
+		}
+	})
+
+	s.Run("cached banner is served without database", func() {
+		// Изменение в обход сервиса, кэш о нём не знает
+		_, err := s.db.Exec(context.Background(), `UPDATE banners SET content = '{"title": "changed"}' WHERE id = $1`, created.BannerID)
+		require.NoError(s.T(), err)
+
+		code, body := s.request("GET", "/user_banner?tag_id=9102&feature_id=9100", userToken, "")
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "cached"}`, body)
+
+		code, body = s.request("GET", "/user_banner?tag_id=9102&feature_id=9100&use_last_revision=true", adminToken, "")
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "changed"}`, body)
+	})
+
+	s.Run("disabled banner cached by admin is not served to user", func() {
+		code, body := s.request("GET", "/user_banner?tag_id=9201&feature_id=9200", adminToken, "")
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "disabled"}`, body)
+
+		_, err := s.cache.Get("9201_9200")
+		require.NoError(s.T(), err)
+
+		code, _ = s.request("GET", "/user_banner?tag_id=9201&feature_id=9200", userToken, "")
+		assert.Equal(s.T(), http.StatusNotFound, code)
+	})
+
+	s.Run("missing pair is cached until banner is created", func() {
+		code, _ := s.request("GET", "/user_banner?tag_id=9301&feature_id=9300", userToken, "")
+		require.Equal(s.T(), http.StatusNotFound, code)
+
+		value, err := s.cache.Get("9301_9300")
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), value)
+
+		code, _ = s.request("POST", "/banner", adminToken,
+			`{"tag_ids": [9301], "feature_id": 9300, "content": {"title": "created"}, "is_active": true}`)
+		require.Equal(s.T(), http.StatusCreated, code)
+
+		code, body := s.request("GET", "/user_banner?tag_id=9301&feature_id=9300", userToken, "")
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "created"}`, body)
+	})
+
+	s.Run("update invalidates cache", func() {
+		code, _, _ := s.requestWithHeaders("PATCH", fmt.Sprintf("/banner/%d", created.BannerID), adminToken, `{"is_active": false}`,
+			map[string]string{"If-Match": "*"})
+		require.Equal(s.T(), http.StatusOK, code)
+
+		code, _ = s.request("GET", "/user_banner?tag_id=9101&feature_id=9100", userToken, "")
+		assert.Equal(s.T(), http.StatusNotFound, code)
+	})
+}
+
+func (s *E2ESuite) TestConditionalGet() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	code, body := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9501], "feature_id": 9500, "content": {"title": "etag"}, "is_active": true}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+
+	s.Run("user banner", func() {
+		code, body, headers := s.requestWithHeaders("GET", "/user_banner?tag_id=9501&feature_id=9500", userToken, "", nil)
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "etag"}`, body)
+		assert.Equal(s.T(), "max-age=300", headers.Get("Cache-Control"))
+		etag := headers.Get("ETag")
+		require.NotEmpty(s.T(), etag)
+
+		code, body, headers = s.requestWithHeaders("GET", "/user_banner?tag_id=9501&feature_id=9500", userToken, "",
+			map[string]string{"If-None-Match": etag})
+		assert.Equal(s.T(), http.StatusNotModified, code)
+		assert.Empty(s.T(), body)
+		assert.Equal(s.T(), etag, headers.Get("ETag"))
+
+		code, _, headers = s.requestWithHeaders("GET", "/user_banner?tag_id=9501&feature_id=9500&use_last_revision=true", adminToken, "",
+			map[string]string{"If-None-Match": etag})
+		assert.Equal(s.T(), http.StatusNotModified, code)
+		assert.Equal(s.T(), "no-cache", headers.Get("Cache-Control"))
+
+		code, _, _ = s.requestWithHeaders("PATCH", fmt.Sprintf("/banner/%d", created.BannerID), adminToken, `{"content": {"title": "changed"}}`,
+			map[string]string{"If-Match": "*"})
+		require.Equal(s.T(), http.StatusOK, code)
+
+		code, body, headers = s.requestWithHeaders("GET", "/user_banner?tag_id=9501&feature_id=9500", userToken, "",
+			map[string]string{"If-None-Match": etag})
+		assert.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{"title": "changed"}`, body)
+		assert.NotEqual(s.T(), etag, headers.Get("ETag"))
+	})
+
+	s.Run("banner list", func() {
+		code, _, headers := s.requestWithHeaders("GET", "/banner?feature_id=9500", adminToken, "", nil)
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.Equal(s.T(), "no-cache", headers.Get("Cache-Control"))
+		etag := headers.Get("ETag")
+		require.NotEmpty(s.T(), etag)
+
+		code, body, _ := s.requestWithHeaders("GET", "/banner?feature_id=9500", adminToken, "",
+			map[string]string{"If-None-Match": etag})
+		assert.Equal(s.T(), http.StatusNotModified, code)
+		assert.Empty(s.T(), body)
+	})
+}
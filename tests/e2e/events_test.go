@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestEvents() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	code, body := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9951], "feature_id": 9950, "content": {"title": "base"}, "is_active": true}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+	statsURL := fmt.Sprintf("/banner/%d/stats", created.BannerID)
+
+	code, _ = s.request("POST", "/events", "", `{"events": []}`)
+	assert.Equal(s.T(), http.StatusUnauthorized, code)
+
+	code, _ = s.request("POST", "/events", userToken, `{"events": [{"type": "hover", "banner_id": 1, "tag_id": 1}]}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, body = s.request("POST", "/events", userToken, fmt.Sprintf(`{"events": [
+		{"type": "impression", "banner_id": %[1]d, "tag_id": 9951},
+		{"type": "impression", "banner_id": %[1]d, "tag_id": 9951},
+		{"type": "click", "banner_id": %[1]d, "tag_id": 9951}
+	]}`, created.BannerID))
+	require.Equal(s.T(), http.StatusAccepted, code)
+	assert.JSONEq(s.T(), `{"accepted": 3}`, body)
+
+	code, _ = s.request("GET", statsURL, userToken, "")
+	assert.Equal(s.T(), http.StatusForbidden, code)
+
+	code, _ = s.request("GET", statsURL+"?days=0", adminToken, "")
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, _ = s.request("GET", "/banner/9999966/stats", adminToken, "")
+	assert.Equal(s.T(), http.StatusNotFound, code)
+
+	// События попадают в базу после сброса буфера
+	var stats []models.DailyStats
+	require.Eventually(s.T(), func() bool {
+		code, body := s.request("GET", statsURL, adminToken, "")
+		return code == http.StatusOK && json.Unmarshal([]byte(body), &stats) == nil && len(stats) == 1 && stats[0].Clicks == 1
+	}, 5*time.Second, 100*time.Millisecond)
+
+	assert.Equal(s.T(), int64(2), stats[0].Impressions)
+	assert.InDelta(s.T(), 0.5, stats[0].CTR, 1e-9)
+	require.Len(s.T(), stats[0].Variants, 1)
+	assert.Equal(s.T(), models.DefaultVariantName, stats[0].Variants[0].Variant)
+}
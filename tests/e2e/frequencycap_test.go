@@ -0,0 +1,44 @@
+package e2e
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestFrequencyCap() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(300, "user")
+	otherUserToken, _ := s.authService.GenerateToken(301, "user")
+	url := "/user_banner?tag_id=9971&feature_id=9970"
+
+	code, _ := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9971], "feature_id": 9970, "content": {"title": "promo"}, "is_active": true,
+		"frequency_cap": {"count": 2, "window": 0}}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, _ = s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9971], "feature_id": 9970, "content": {"title": "promo"}, "is_active": true,
+		"frequency_cap": {"count": 2, "window": 86400}}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	for i := 0; i < 2; i++ {
+		code, _, header := s.requestWithHeaders("GET", url, userToken, "", nil)
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.Equal(s.T(), "no-cache", header.Get("Cache-Control"))
+	}
+
+	// Лимит исчерпан
+	code, _ = s.request("GET", url, userToken, "")
+	assert.Equal(s.T(), http.StatusNotFound, code)
+	code, body := s.request("POST", "/user_banners", userToken, `{"tag_id": 9971, "feature_ids": [9970]}`)
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"9971_9970": {"status": "not_found"}}`, body)
+
+	// Лимит считается для каждого пользователя отдельно, показы админам не считаются
+	code, _ = s.request("GET", url, otherUserToken, "")
+	assert.Equal(s.T(), http.StatusOK, code)
+	code, _ = s.request("GET", url, adminToken, "")
+	assert.Equal(s.T(), http.StatusOK, code)
+}
@@ -115,5 +115,30 @@ func Cleanup(db *postgres.Postgres) error {
 	}
 
 	_, err = db.Exec(context.Background(), "DELETE FROM banner_mappings")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM jobs")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM dead_letters")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM scheduled_changes")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM banner_events")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(context.Background(), "DELETE FROM queue_messages")
 	return err
 }
@@ -0,0 +1,72 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestLocalizedContent() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(400, "user")
+	url := "/user_banner?tag_id=9981&feature_id=9980"
+
+	code, _ := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9981], "feature_id": 9980, "content": {"title": "base"}, "is_active": true,
+		"localized_content": {"EN": {"title": "hello"}}}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, body := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9981], "feature_id": 9980, "content": {"title": "base"}, "is_active": true,
+		"localized_content": {"en": {"title": "hello"}, "ru": {"title": "привет"}}}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+
+	// Перевод выбирается по Accept-Language, регион отбрасывается
+	code, body, header := s.requestWithHeaders("GET", url, userToken, "", map[string]string{"Accept-Language": "ru-RU, en;q=0.8"})
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"title": "привет"}`, body)
+	assert.Equal(s.T(), "ru", header.Get("Content-Language"))
+	assert.Contains(s.T(), header.Values("Vary"), "Accept-Language")
+
+	// Параметр locale важнее заголовка, неизвестный язык уходит в цепочку по умолчанию
+	code, body, header = s.requestWithHeaders("GET", url+"&locale=de", userToken, "", map[string]string{"Accept-Language": "ru"})
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"title": "hello"}`, body)
+	assert.Equal(s.T(), "en", header.Get("Content-Language"))
+
+	// PATCH сливает переводы, null удаляет перевод
+	code, _, header = s.requestWithHeaders("GET", fmt.Sprintf("/banner/%d", created.BannerID), adminToken, "", nil)
+	require.Equal(s.T(), http.StatusOK, code)
+	code, _, _ = s.requestWithHeaders("PATCH", fmt.Sprintf("/banner/%d", created.BannerID), adminToken,
+		`{"localized_content": {"en": null, "de": {"title": "hallo"}}}`, map[string]string{"If-Match": header.Get("ETag")})
+	require.Equal(s.T(), http.StatusOK, code)
+
+	code, body = s.request("POST", "/user_banners?locale=fr", userToken, `{"tag_id": 9981, "feature_ids": [9980]}`)
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"9981_9980": {"status": "ok", "content": {"title": "base"}, "variant": "default"}}`, body)
+
+	code, body = s.request("POST", "/user_banners?locale=de-AT", userToken, `{"tag_id": 9981, "feature_ids": [9980]}`)
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"9981_9980": {"status": "ok", "content": {"title": "hallo"}, "variant": "default", "locale": "de"}}`, body)
+
+	// В историю попадает содержимое вместе с переводами
+	code, body = s.request("GET", fmt.Sprintf("/banner/%d/versions", created.BannerID), adminToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+
+	var versions []struct {
+		LocalizedContent map[string]json.RawMessage `json:"localized_content"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &versions))
+	require.NotEmpty(s.T(), versions)
+	require.Len(s.T(), versions[0].LocalizedContent, 2)
+	assert.JSONEq(s.T(), `{"title": "hallo"}`, string(versions[0].LocalizedContent["de"]))
+	assert.JSONEq(s.T(), `{"title": "привет"}`, string(versions[0].LocalizedContent["ru"]))
+}
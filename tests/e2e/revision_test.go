@@ -0,0 +1,59 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestBannerRevision() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+
+	code, body := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9601], "feature_id": 9600, "content": {"title": "v1"}, "is_active": true}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+	url := fmt.Sprintf("/banner/%d", created.BannerID)
+
+	code, body, headers := s.requestWithHeaders("GET", url, adminToken, "", nil)
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.Equal(s.T(), `"1"`, headers.Get("ETag"))
+
+	var banner struct {
+		Revision int64 `json:"revision"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &banner))
+	assert.Equal(s.T(), int64(1), banner.Revision)
+
+	// Первый админ сохраняет правку по прочитанной ревизии
+	code, _, headers = s.requestWithHeaders("PATCH", url, adminToken, `{"content": {"title": "v2"}}`,
+		map[string]string{"If-Match": `"1"`})
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.Equal(s.T(), `"2"`, headers.Get("ETag"))
+
+	// Второй админ читал ту же ревизию, его правка не затирает первую
+	code, body, headers = s.requestWithHeaders("PATCH", url, adminToken, `{"content": {"title": "other"}}`,
+		map[string]string{"If-Match": `"1"`})
+	require.Equal(s.T(), http.StatusPreconditionFailed, code)
+	assert.Equal(s.T(), `"2"`, headers.Get("ETag"))
+
+	var mismatch struct {
+		Revision int64 `json:"revision"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &mismatch))
+	assert.Equal(s.T(), int64(2), mismatch.Revision)
+
+	code, body, _ = s.requestWithHeaders("GET", url, adminToken, "", map[string]string{"If-None-Match": `"1"`})
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.Contains(s.T(), body, `"v2"`)
+
+	code, _, _ = s.requestWithHeaders("GET", url, adminToken, "", map[string]string{"If-None-Match": `"2"`})
+	assert.Equal(s.T(), http.StatusNotModified, code)
+}
@@ -0,0 +1,73 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestScheduledChanges() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	code, body := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9801], "feature_id": 9800, "content": {"price": 100}, "is_active": true}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+	url := fmt.Sprintf("/banner/%d/scheduled_changes", created.BannerID)
+	userURL := "/user_banner?tag_id=9801&feature_id=9800"
+
+	// Баннер попадает в кэш до применения изменения
+	code, _ = s.request("GET", userURL, userToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+
+	code, _ = s.request("POST", url, adminToken, `{"apply_at": 1712000000, "change": {}}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, _ = s.request("POST", "/banner/9999988/scheduled_changes", adminToken,
+		`{"apply_at": 1712000000, "change": {"is_active": false}}`)
+	assert.Equal(s.T(), http.StatusNotFound, code)
+
+	later := time.Now().Add(time.Hour).Unix()
+	code, body = s.request("POST", url, adminToken, fmt.Sprintf(`{"apply_at": %d, "change": {"is_active": false}}`, later))
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var scheduled struct {
+		ID int64 `json:"scheduled_change_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &scheduled))
+
+	code, body = s.request("GET", url, adminToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.Contains(s.T(), body, fmt.Sprintf(`"apply_at":%d`, later))
+
+	code, _ = s.request("DELETE", fmt.Sprintf("%s/%d", url, scheduled.ID), adminToken, "")
+	assert.Equal(s.T(), http.StatusNoContent, code)
+	code, _ = s.request("DELETE", fmt.Sprintf("%s/%d", url, scheduled.ID), adminToken, "")
+	assert.Equal(s.T(), http.StatusNotFound, code)
+
+	// Наступившее изменение применяется планировщиком и сбрасывает кэш
+	code, _ = s.request("POST", url, adminToken,
+		fmt.Sprintf(`{"apply_at": %d, "change": {"content": {"price": 90}}}`, time.Now().Unix()))
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	require.Eventually(s.T(), func() bool {
+		code, body := s.request("GET", userURL, userToken, "")
+		var content struct {
+			Price int `json:"price"`
+		}
+		return code == http.StatusOK && json.Unmarshal([]byte(body), &content) == nil && content.Price == 90
+	}, 5*time.Second, 100*time.Millisecond)
+
+	code, body = s.request("GET", url, adminToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `[]`, body)
+}
@@ -0,0 +1,64 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestTemplates() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(500, "user")
+	userURL := "/user_banner?tag_id=9991&feature_id=9990"
+
+	// Сломанные плейсхолдеры отклоняются при создании
+	code, _ := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9991], "feature_id": 9990, "content": {"title": "{{claims.user_id"}, "is_active": true}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	code, _ = s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9991], "feature_id": 9990, "content": {"title": "{{env.SECRET}}"}, "is_active": true}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	deadline := time.Now().Add(time.Hour).Unix()
+	code, body := s.request("POST", "/banner", adminToken, fmt.Sprintf(
+		`{"tag_ids": [9991], "feature_id": 9990, "is_active": true,
+		"content": {"title": "Привет, {{query.name|друг}}!", "user": "{{claims.user_id}}", "left": "{{server.countdown.%d}}"}}`, deadline))
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+
+	var content struct {
+		Title string `json:"title"`
+		User  string `json:"user"`
+		Left  string `json:"left"`
+	}
+	code, body, header := s.requestWithHeaders("GET", userURL+"&name="+url.QueryEscape("Анна"), userToken, "", nil)
+	require.Equal(s.T(), http.StatusOK, code)
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &content))
+	assert.Equal(s.T(), "Привет, Анна!", content.Title)
+	assert.Equal(s.T(), "500", content.User)
+	left, err := strconv.ParseInt(content.Left, 10, 64)
+	require.NoError(s.T(), err)
+	assert.InDelta(s.T(), 3600, left, 60)
+	assert.Equal(s.T(), "no-cache", header.Get("Cache-Control"))
+
+	code, body = s.request("POST", "/user_banners", userToken, `{"tag_id": 9991, "feature_ids": [9990]}`)
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.Contains(s.T(), body, `"title":"Привет, друг!"`)
+
+	// PATCH проверяет плейсхолдеры так же, как создание
+	code, _, header = s.requestWithHeaders("GET", fmt.Sprintf("/banner/%d", created.BannerID), adminToken, "", nil)
+	require.Equal(s.T(), http.StatusOK, code)
+	code, _, _ = s.requestWithHeaders("PATCH", fmt.Sprintf("/banner/%d", created.BannerID), adminToken,
+		`{"content": {"title": "{{server.hostname}}"}}`, map[string]string{"If-Match": header.Get("ETag")})
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+}
@@ -0,0 +1,55 @@
+package e2e
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestUserBanners() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	code, _ := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9401], "feature_id": 9410, "content": {"title": "first"}, "is_active": true}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+	code, _ = s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9401], "feature_id": 9420, "content": {"title": "disabled"}, "is_active": false}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	s.Run("pairs", func() {
+		code, body := s.request("POST", "/user_banners", userToken,
+			`{"pairs": [{"tag_id": 9401, "feature_id": 9410}, {"tag_id": 9401, "feature_id": 9420}, {"tag_id": 9401, "feature_id": 9430}]}`)
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{
+			"9401_9410": {"status": "ok", "content": {"title": "first"}},
+			"9401_9420": {"status": "inactive"},
+			"9401_9430": {"status": "not_found"}
+		}`, body)
+	})
+
+	s.Run("one tag with many features", func() {
+		code, body := s.request("POST", "/user_banners", adminToken,
+			`{"tag_id": 9401, "feature_ids": [9410, 9420]}`)
+		require.Equal(s.T(), http.StatusOK, code)
+		assert.JSONEq(s.T(), `{
+			"9401_9410": {"status": "ok", "content": {"title": "first"}},
+			"9401_9420": {"status": "ok", "content": {"title": "disabled"}}
+		}`, body)
+	})
+
+	s.Run("bad request", func() {
+		code, _ := s.request("POST", "/user_banners", userToken, `{"pairs": []}`)
+		assert.Equal(s.T(), http.StatusBadRequest, code)
+
+		code, _ = s.request("POST", "/user_banners", userToken,
+			`{"tag_id": 9401, "feature_ids": [9410], "pairs": [{"tag_id": 1, "feature_id": 1}]}`)
+		assert.Equal(s.T(), http.StatusBadRequest, code)
+	})
+
+	s.Run("unauthorized", func() {
+		code, _ := s.request("POST", "/user_banners", "", `{"tag_id": 9401, "feature_ids": [9410]}`)
+		assert.Equal(s.T(), http.StatusUnauthorized, code)
+	})
+}
@@ -0,0 +1,46 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestVariants() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	url := "/user_banner?tag_id=9901&feature_id=9900"
+
+	code, _ := s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9901], "feature_id": 9900, "content": {"title": "base"}, "is_active": true,
+		"variants": [{"name": "default", "content": {"title": "x"}, "weight": 1}]}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, _ = s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9901], "feature_id": 9900, "content": {"title": "base"}, "is_active": true,
+		"variants": [{"name": "a", "content": {"title": "a"}, "weight": 1}, {"name": "b", "content": {"title": "b"}, "weight": 1}]}`)
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	served := make(map[string]bool)
+	for userID := 100; userID < 120; userID++ {
+		userToken, _ := s.authService.GenerateToken(userID, "user")
+
+		code, body, header := s.requestWithHeaders("GET", url, userToken, "", nil)
+		require.Equal(s.T(), http.StatusOK, code)
+		variant := header.Get("X-Banner-Variant")
+		require.Contains(s.T(), []string{"a", "b"}, variant)
+		served[variant] = true
+
+		var content struct {
+			Title string `json:"title"`
+		}
+		require.NoError(s.T(), json.Unmarshal([]byte(body), &content))
+		assert.Equal(s.T(), variant, content.Title)
+
+		// Повторный запрос того же пользователя получает тот же вариант
+		_, _, header = s.requestWithHeaders("GET", url, userToken, "", nil)
+		assert.Equal(s.T(), variant, header.Get("X-Banner-Variant"))
+	}
+	assert.Len(s.T(), served, 2)
+}
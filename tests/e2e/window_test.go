@@ -0,0 +1,58 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *E2ESuite) TestActiveWindow() {
+	adminToken, _ := s.authService.GenerateToken(1, "admin")
+	userToken, _ := s.authService.GenerateToken(2, "user")
+
+	activeFrom := time.Now().Add(time.Hour).Unix()
+	code, body := s.request("POST", "/banner", adminToken,
+		fmt.Sprintf(`{"tag_ids": [9701], "feature_id": 9700, "content": {"title": "campaign"}, "is_active": true, "active_from": %d}`, activeFrom))
+	require.Equal(s.T(), http.StatusCreated, code)
+
+	var created struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(body), &created))
+	url := fmt.Sprintf("/banner/%d", created.BannerID)
+	userURL := "/user_banner?tag_id=9701&feature_id=9700&use_last_revision=true"
+
+	// До начала окна баннер для пользователя как выключенный, админ его видит
+	code, _ = s.request("GET", userURL, userToken, "")
+	assert.Equal(s.T(), http.StatusNotFound, code)
+
+	code, body = s.request("GET", userURL, adminToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"title": "campaign"}`, body)
+
+	code, body = s.request("GET", url, adminToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.Contains(s.T(), body, fmt.Sprintf(`"active_from":%d`, activeFrom))
+
+	// Окно, которое заканчивается раньше, чем начинается
+	code, _ = s.request("POST", "/banner", adminToken,
+		`{"tag_ids": [9702], "feature_id": 9700, "content": {}, "is_active": true, "active_from": 200, "active_until": 100}`)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	code, _, _ = s.requestWithHeaders("PATCH", url, adminToken,
+		fmt.Sprintf(`{"active_until": %d}`, activeFrom-60), map[string]string{"If-Match": "*"})
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+
+	// Нулевая граница снимается, баннер сразу показывается
+	code, _, _ = s.requestWithHeaders("PATCH", url, adminToken, `{"active_from": 0}`,
+		map[string]string{"If-Match": "*"})
+	require.Equal(s.T(), http.StatusOK, code)
+
+	code, body = s.request("GET", userURL, userToken, "")
+	require.Equal(s.T(), http.StatusOK, code)
+	assert.JSONEq(s.T(), `{"title": "campaign"}`, body)
+}
@@ -0,0 +1,77 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestJobs() {
+	s.Run("Jobs", func() {
+		jobID, err := s.jobRepo.CreateJob(context.Background())
+		require.NoError(s.T(), err)
+
+		job, err := s.jobRepo.GetJob(context.Background(), jobID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), models.JobQueued, job.Status)
+		assert.Empty(s.T(), job.Error)
+
+		err = s.jobRepo.UpdateJobStatus(context.Background(), jobID, models.JobFailed, "some error")
+		require.NoError(s.T(), err)
+
+		job, err = s.jobRepo.GetJob(context.Background(), jobID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), models.JobFailed, job.Status)
+		assert.Equal(s.T(), "some error", job.Error)
+
+		_, err = s.jobRepo.GetJob(context.Background(), jobID+1000)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+
+		err = s.jobRepo.UpdateJobStatus(context.Background(), jobID+1000, models.JobDone, "")
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+	})
+}
+
+func (s *BannerRepositoryTestSuite) TestDeadLetters() {
+	s.Run("DeadLetters", func() {
+		task := json.RawMessage(`{"job_id": 1, "attempt": 5, "filter": {"banner_id": 1, "feature_id": null, "tag_id": null}}`)
+
+		id, err := s.deadLetterRepo.AddDeadLetter(context.Background(), 1, task, "some error", 5)
+		require.NoError(s.T(), err)
+
+		deadLetters, err := s.deadLetterRepo.ListDeadLetters(context.Background(), n.NullUint64From(10), n.NullUint64{})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), deadLetters, 1)
+		assert.Equal(s.T(), id, deadLetters[0].ID)
+		assert.Equal(s.T(), int64(1), deadLetters[0].JobID)
+		assert.JSONEq(s.T(), string(task), string(deadLetters[0].Task))
+		assert.Equal(s.T(), "some error", deadLetters[0].Error)
+		assert.Equal(s.T(), 5, deadLetters[0].Attempts)
+
+		err = s.deadLetterRepo.DeleteDeadLetter(context.Background(), id)
+		require.NoError(s.T(), err)
+
+		deadLetters, err = s.deadLetterRepo.ListDeadLetters(context.Background(), n.NullUint64From(10), n.NullUint64{})
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), deadLetters)
+
+		err = s.deadLetterRepo.DeleteDeadLetter(context.Background(), id)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+
+		id, err = s.deadLetterRepo.AddDeadLetter(context.Background(), 1, task, "some error", 5)
+		require.NoError(s.T(), err)
+
+		taken, err := s.deadLetterRepo.TakeDeadLetter(context.Background(), id)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), id, taken.ID)
+		assert.JSONEq(s.T(), string(task), string(taken.Task))
+
+		_, err = s.deadLetterRepo.TakeDeadLetter(context.Background(), id)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+	})
+}
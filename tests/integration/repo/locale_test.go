@@ -0,0 +1,61 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestLocalizedContent() {
+	ctx := context.Background()
+	localizedContent := models.LocalizedContent{
+		"en": json.RawMessage(`{"title": "hello"}`),
+		"ru": json.RawMessage(`{"title": "привет"}`),
+	}
+
+	bannerID, err := s.repo.CreateBanner(ctx, models.BannerInput{TagIDs: []int64{5401}, FeatureID: 6401, Content: json.RawMessage(`{"title": "base"}`), IsActive: true, LocalizedContent: localizedContent})
+	require.NoError(s.T(), err)
+
+	s.Run("translations are stored", func() {
+		banner, err := s.repo.GetBanner(ctx, 5401, 6401, true)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), localizedContent, banner.LocalizedContent)
+	})
+
+	s.Run("patch merges translations and writes history", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{LocalizedContent: models.LocalizedContent{
+			"ru": json.RawMessage(`null`),
+			"de": json.RawMessage(`{"title": "hallo"}`),
+		}})
+		require.NoError(s.T(), err)
+
+		banner, err := s.repo.GetBannerByID(ctx, bannerID)
+		require.NoError(s.T(), err)
+		assert.JSONEq(s.T(), `{"title": "base"}`, string(banner.Content))
+		assert.Equal(s.T(), models.LocalizedContent{
+			"en": json.RawMessage(`{"title": "hello"}`),
+			"de": json.RawMessage(`{"title": "hallo"}`),
+		}, banner.LocalizedContent)
+
+		version := getLastVersion(s.repo, bannerID)
+		assert.JSONEq(s.T(), `{"title": "base"}`, string(version.Content))
+		assert.Equal(s.T(), banner.LocalizedContent, version.LocalizedContent)
+	})
+
+	s.Run("removing last translation clears it", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{LocalizedContent: models.LocalizedContent{
+			"en": json.RawMessage(`null`),
+			"de": json.RawMessage(`null`),
+		}})
+		require.NoError(s.T(), err)
+
+		banners, err := s.repo.ListBanners(ctx, n.NullInt64From(6401), n.NullInt64{}, n.NullUint64{}, n.NullUint64{})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), banners, 1)
+		assert.Nil(s.T(), banners[0].LocalizedContent)
+	})
+}
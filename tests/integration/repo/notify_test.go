@@ -0,0 +1,85 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveChange(s *BannerRepositoryTestSuite, changes <-chan models.BannerChange) models.BannerChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(3 * time.Second):
+		s.T().Fatalf("banner change was not delivered")
+		return models.BannerChange{}
+	}
+}
+
+func (s *BannerRepositoryTestSuite) TestBannerChanges() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan models.BannerChange, 10)
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- s.repo.ListenBannerChanges(ctx, func(change models.BannerChange) {
+			changes <- change
+		})
+	}()
+	// Ожидание, пока подписка выполнит LISTEN
+	time.Sleep(200 * time.Millisecond)
+
+	var bannerID int64
+
+	s.Run("create notifies new tags", func() {
+		var err error
+		bannerID, err = s.repo.CreateBanner(context.Background(), models.BannerInput{TagIDs: []int64{3001, 3002}, FeatureID: 4001, Content: json.RawMessage(`{}`), IsActive: true})
+		require.NoError(s.T(), err)
+
+		change := receiveChange(s, changes)
+		assert.Equal(s.T(), bannerID, change.BannerID)
+		assert.ElementsMatch(s.T(), []int64{4001}, change.FeatureIDs)
+		assert.ElementsMatch(s.T(), []int64{3001, 3002}, change.TagIDs)
+	})
+
+	s.Run("update notifies old and new tags", func() {
+		_, err := s.repo.UpdateBanner(context.Background(), bannerID, n.NullInt64{}, models.BannerPatch{TagIDs: []int64{3003}, FeatureID: n.NullInt64From(4002)})
+		require.NoError(s.T(), err)
+
+		change := receiveChange(s, changes)
+		assert.Equal(s.T(), bannerID, change.BannerID)
+		assert.ElementsMatch(s.T(), []int64{4001, 4002}, change.FeatureIDs)
+		assert.ElementsMatch(s.T(), []int64{3001, 3002, 3003}, change.TagIDs)
+	})
+
+	s.Run("delete notifies tags", func() {
+		err := s.repo.DeleteBanner(context.Background(), bannerID)
+		require.NoError(s.T(), err)
+
+		change := receiveChange(s, changes)
+		assert.Equal(s.T(), bannerID, change.BannerID)
+		assert.ElementsMatch(s.T(), []int64{4002}, change.FeatureIDs)
+		assert.ElementsMatch(s.T(), []int64{3003}, change.TagIDs)
+	})
+
+	s.Run("rolled back changes are not notified", func() {
+		_, err := s.repo.CreateBanner(context.Background(), models.BannerInput{TagIDs: []int64{3004, 3004}, FeatureID: 4003, Content: json.RawMessage(`{}`), IsActive: true})
+		require.ErrorIs(s.T(), err, errs.ErrUniqueViolation)
+
+		select {
+		case change := <-changes:
+			s.T().Fatalf("unexpected banner change: %+v", change)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	cancel()
+	assert.NoError(s.T(), <-listenErr)
+}
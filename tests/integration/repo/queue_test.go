@@ -0,0 +1,73 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/broker"
+	"backend-trainee-assignment-2024/internal/config"
+	"backend-trainee-assignment-2024/internal/storage/postgres"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(s *BannerRepositoryTestSuite, messages <-chan broker.Message) broker.Message {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(3 * time.Second):
+		s.T().Fatalf("message was not delivered")
+		return broker.Message{}
+	}
+}
+
+func (s *BannerRepositoryTestSuite) TestPostgresQueue() {
+	queue := postgres.NewPostgresQueue(s.db, config.Queue{
+		PollInterval:      50 * time.Millisecond,
+		VisibilityTimeout: time.Second,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer queue.Close()
+
+	messages, err := queue.Consume("test_queue")
+	require.NoError(s.T(), err)
+
+	s.Run("publish and ack", func() {
+		require.NoError(s.T(), queue.Publish([]byte("first"), "test_queue"))
+
+		msg := receive(s, messages)
+		assert.Equal(s.T(), []byte("first"), msg.Body)
+		require.NoError(s.T(), msg.Ack())
+	})
+
+	s.Run("nack with requeue redelivers message", func() {
+		require.NoError(s.T(), queue.Publish([]byte("second"), "test_queue"))
+
+		msg := receive(s, messages)
+		require.NoError(s.T(), msg.Nack(true))
+
+		msg = receive(s, messages)
+		assert.Equal(s.T(), []byte("second"), msg.Body)
+		require.NoError(s.T(), msg.Ack())
+	})
+
+	s.Run("unacked message is redelivered after visibility timeout", func() {
+		require.NoError(s.T(), queue.Publish([]byte("third"), "test_queue"))
+
+		receive(s, messages)
+
+		msg := receive(s, messages)
+		assert.Equal(s.T(), []byte("third"), msg.Body)
+		require.NoError(s.T(), msg.Ack())
+	})
+
+	s.Run("delayed message is delivered after delay", func() {
+		published := time.Now()
+		require.NoError(s.T(), queue.PublishDelayed([]byte("delayed"), "test_queue", time.Second))
+
+		msg := receive(s, messages)
+		assert.Equal(s.T(), []byte("delayed"), msg.Body)
+		assert.GreaterOrEqual(s.T(), time.Since(published), time.Second)
+		require.NoError(s.T(), msg.Ack())
+	})
+}
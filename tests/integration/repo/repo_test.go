@@ -19,8 +19,10 @@ import (
 
 type BannerRepositoryTestSuite struct {
 	suite.Suite
-	db   *postgres.Postgres
-	repo *repo.BannerRepository
+	db             *postgres.Postgres
+	repo           *repo.BannerRepository
+	jobRepo        *repo.JobRepository
+	deadLetterRepo *repo.DeadLetterRepository
 }
 
 func TestBannerRepository(t *testing.T) {
@@ -54,6 +56,8 @@ func (s *BannerRepositoryTestSuite) SetupSuite() {
 	}
 
 	s.repo = repo.NewBannerRepository(s.db)
+	s.jobRepo = repo.NewJobRepository(s.db)
+	s.deadLetterRepo = repo.NewDeadLetterRepository(s.db)
 }
 
 func (s *BannerRepositoryTestSuite) TearDownSuite() {
@@ -82,6 +86,24 @@ func (s *BannerRepositoryTestSuite) Test0_GetBanner() {
 					IsActive:  true,
 					CreatedAt: 1672531200,
 					UpdatedAt: 1672531320,
+					FeatureID: 1001,
+					TagIds:    []int64{2001},
+				},
+				expectedErr: nil,
+			},
+			{
+				name:       "inactive banner for admin",
+				tagID:      2002,
+				featureID:  1002,
+				onlyActive: false,
+				expected: models.Banner{
+					ID:        2,
+					Content:   json.RawMessage(`{"title": "title", "text": "some_text", "url": "some_url"}`),
+					IsActive:  false,
+					CreatedAt: 1672617600,
+					UpdatedAt: 1672617600,
+					FeatureID: 1002,
+					TagIds:    []int64{2002},
 				},
 				expectedErr: nil,
 			},
@@ -118,6 +140,8 @@ func (s *BannerRepositoryTestSuite) Test0_GetBanner() {
 				assert.Equal(s.T(), test.expected.IsActive, banner.IsActive)
 				assert.Equal(s.T(), test.expected.CreatedAt, banner.CreatedAt)
 				assert.Equal(s.T(), test.expected.UpdatedAt, banner.UpdatedAt)
+				assert.Equal(s.T(), test.expected.FeatureID, banner.FeatureID)
+				assert.ElementsMatch(s.T(), test.expected.TagIds, banner.TagIds)
 			})
 		}
 	})
@@ -359,6 +383,7 @@ func (s *BannerRepositoryTestSuite) Test3_CreateBanner() {
 			featureID   int64
 			content     json.RawMessage
 			isActive    bool
+			window      models.ActiveWindow
 			expected    int64
 			expectedErr error
 		}{
@@ -378,17 +403,35 @@ func (s *BannerRepositoryTestSuite) Test3_CreateBanner() {
 				isActive:    true,
 				expectedErr: errs.ErrUniqueViolation,
 			},
+			{
+				name:      "successful create banner with active window",
+				tagIDs:    []int64{100004},
+				featureID: 100001,
+				content:   json.RawMessage(`{"title": "campaign banner"}`),
+				isActive:  true,
+				window: models.ActiveWindow{
+					ActiveFrom:  n.NullInt64From(1712000000),
+					ActiveUntil: n.NullInt64From(1713000000),
+				},
+				expectedErr: nil,
+			},
+			{
+				name:      "empty active window",
+				tagIDs:    []int64{100005},
+				featureID: 100001,
+				content:   json.RawMessage(`{"title": "campaign banner"}`),
+				isActive:  true,
+				window: models.ActiveWindow{
+					ActiveFrom:  n.NullInt64From(1713000000),
+					ActiveUntil: n.NullInt64From(1712000000),
+				},
+				expectedErr: errs.ErrInvalidValue,
+			},
 		}
 
 		for _, test := range tests {
 			s.Run(test.name, func() {
-				id, err := s.repo.CreateBanner(
-					context.Background(),
-					test.tagIDs,
-					test.featureID,
-					test.content,
-					test.isActive,
-				)
+				id, err := s.repo.CreateBanner(context.Background(), models.BannerInput{TagIDs: test.tagIDs, FeatureID: test.featureID, Content: test.content, IsActive: test.isActive, ActiveWindow: test.window})
 
 				if test.expectedErr != nil {
 					assert.ErrorIs(s.T(), err, test.expectedErr)
@@ -406,7 +449,7 @@ func (s *BannerRepositoryTestSuite) Test3_CreateBanner() {
 				assert.Equal(s.T(), test.isActive, banner.IsActive)
 				assert.ElementsMatch(s.T(), test.tagIDs, banner.TagIds)
 				assert.Equal(s.T(), test.featureID, banner.FeatureID)
-
+				assert.Equal(s.T(), test.window, banner.ActiveWindow)
 			})
 		}
 	})
@@ -484,7 +527,7 @@ func (s *BannerRepositoryTestSuite) Test4_UpdateBanner() {
 				// Получение первой версии баннера перед обновлением
 				beforeUpdate := getLastVersion(s.repo, test.id)
 
-				err := s.repo.UpdateBanner(context.Background(), test.id, test.tagIDs, test.featureID, test.content, test.isActive)
+				revision, err := s.repo.UpdateBanner(context.Background(), test.id, n.NullInt64{}, models.BannerPatch{TagIDs: test.tagIDs, FeatureID: test.featureID, Content: test.content, IsActive: test.isActive})
 				if test.expectedErr != nil {
 					assert.ErrorIs(s.T(), err, test.expectedErr)
 					return
@@ -507,6 +550,7 @@ func (s *BannerRepositoryTestSuite) Test4_UpdateBanner() {
 				require.NoError(s.T(), err)
 
 				assert.Equal(s.T(), test.id, banner.ID)
+				assert.Equal(s.T(), revision, banner.Revision)
 				if test.content != nil {
 					assert.JSONEq(s.T(), string(test.content), string(banner.Content))
 				}
@@ -526,6 +570,27 @@ func (s *BannerRepositoryTestSuite) Test4_UpdateBanner() {
 			})
 		}
 	})
+
+	s.Run("UpdateBanner with revision", func() {
+		banner, err := s.repo.GetBannerByID(context.Background(), 8)
+		require.NoError(s.T(), err)
+
+		revision, err := s.repo.UpdateBanner(context.Background(), 8, n.NullInt64From(banner.Revision), models.BannerPatch{IsActive: n.NullBool{Bool: false, Valid: true}})
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), banner.Revision+1, revision)
+
+		// Второе изменение, сделанное по той же прочитанной версии, отклоняется
+		_, err = s.repo.UpdateBanner(context.Background(), 8, n.NullInt64From(banner.Revision), models.BannerPatch{IsActive: n.NullBool{Bool: true, Valid: true}})
+		assert.ErrorIs(s.T(), err, errs.ErrRevisionMismatch)
+
+		updated, err := s.repo.GetBannerByID(context.Background(), 8)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), revision, updated.Revision)
+		assert.False(s.T(), updated.IsActive)
+
+		_, err = s.repo.UpdateBanner(context.Background(), 9999966, n.NullInt64From(1), models.BannerPatch{IsActive: n.NullBool{Bool: true, Valid: true}})
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+	})
 }
 
 func getLastVersion(repo *repo.BannerRepository, bannerID int64) models.BannerVersion {
@@ -686,3 +751,97 @@ func getVersion(repo *repo.BannerRepository, bannerID int64, updatedAt models.Un
 	}
 	return models.BannerVersion{}
 }
+
+func (s *BannerRepositoryTestSuite) TestDeleteBanners() {
+	s.Run("DeleteBanners", func() {
+		tests := []struct {
+			name      string
+			featureID n.NullInt64
+			tagID     n.NullInt64
+			limit     uint64
+			expected  int
+		}{
+			{
+				name:     "delete first batch by tag",
+				tagID:    n.NullInt64From(2005),
+				limit:    1,
+				expected: 1,
+			},
+			{
+				name:     "delete second batch by tag",
+				tagID:    n.NullInt64From(2005),
+				limit:    1,
+				expected: 1,
+			},
+			{
+				name:     "nothing left to delete by tag",
+				tagID:    n.NullInt64From(2005),
+				limit:    1,
+				expected: 0,
+			},
+			{
+				name:      "delete by feature and tag",
+				featureID: n.NullInt64From(1007),
+				tagID:     n.NullInt64From(2008),
+				limit:     100,
+				expected:  1,
+			},
+		}
+
+		for _, test := range tests {
+			s.Run(test.name, func() {
+				deleted, err := s.repo.DeleteBanners(context.Background(), test.featureID, test.tagID, test.limit)
+				require.NoError(s.T(), err)
+				assert.Len(s.T(), deleted, test.expected)
+
+				// Удалённые баннеры возвращаются вместе с фичей и тегами
+				for _, banner := range deleted {
+					if test.featureID.Valid {
+						assert.Equal(s.T(), test.featureID.Int64, banner.FeatureID)
+					}
+					if test.tagID.Valid {
+						assert.Contains(s.T(), banner.TagIds, test.tagID.Int64)
+					}
+				}
+			})
+		}
+
+		// Баннер удаляется целиком, вместе со всеми тегами
+		_, err := s.repo.GetBanner(context.Background(), 2007, 1007, false)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+	})
+}
+
+func (s *BannerRepositoryTestSuite) TestGetBanners() {
+	ctx := context.Background()
+
+	activeID, err := s.repo.CreateBanner(ctx, models.BannerInput{TagIDs: []int64{5501}, FeatureID: 6501, Content: json.RawMessage(`{"title": "active"}`), IsActive: true})
+	require.NoError(s.T(), err)
+	inactiveID, err := s.repo.CreateBanner(ctx, models.BannerInput{TagIDs: []int64{5502}, FeatureID: 6502, Content: json.RawMessage(`{"title": "inactive"}`), IsActive: false})
+	require.NoError(s.T(), err)
+
+	banners, err := s.repo.GetBanners(ctx, []models.BannerKey{
+		{TagID: 5501, FeatureID: 6501},
+		{TagID: 5502, FeatureID: 6502},
+		{TagID: 5501, FeatureID: 6502},
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), banners, 2)
+
+	byID := make(map[int64]models.Banner)
+	for _, banner := range banners {
+		byID[banner.ID] = banner
+	}
+
+	assert.Equal(s.T(), int64(6501), byID[activeID].FeatureID)
+	assert.Equal(s.T(), []int64{5501}, byID[activeID].TagIds)
+	assert.True(s.T(), byID[activeID].IsActive)
+	assert.JSONEq(s.T(), `{"title": "active"}`, string(byID[activeID].Content))
+
+	// Выключенный баннер тоже возвращается, активность проверяет сервис
+	assert.False(s.T(), byID[inactiveID].IsActive)
+
+	banners, err = s.repo.GetBanners(ctx, []models.BannerKey{{TagID: 5501, FeatureID: 6502}})
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), banners)
+}
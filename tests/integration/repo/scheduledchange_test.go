@@ -0,0 +1,66 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/repo"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestScheduledChanges() {
+	changeRepo := repo.NewScheduledChangeRepository(s.db)
+	ctx := context.Background()
+
+	bannerID, err := s.repo.CreateBanner(ctx, models.BannerInput{TagIDs: []int64{5101}, FeatureID: 6101, Content: json.RawMessage(`{"price":100}`), IsActive: true})
+	require.NoError(s.T(), err)
+
+	past := models.UnixTime(time.Now().Add(-time.Minute).Unix())
+	future := models.UnixTime(time.Now().Add(time.Hour).Unix())
+	patch := models.BannerPatch{Content: json.RawMessage(`{"price":90}`)}
+
+	dueID, err := changeRepo.CreateScheduledChange(ctx, bannerID, past, patch)
+	require.NoError(s.T(), err)
+	futureID, err := changeRepo.CreateScheduledChange(ctx, bannerID, future, patch)
+	require.NoError(s.T(), err)
+
+	s.Run("missing banner", func() {
+		_, err := changeRepo.CreateScheduledChange(ctx, 9999977, past, patch)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+	})
+
+	s.Run("list pending changes", func() {
+		changes, err := changeRepo.ListScheduledChanges(ctx, bannerID)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), changes, 2)
+		assert.Equal(s.T(), dueID, changes[0].ID)
+		assert.Equal(s.T(), past, changes[0].ApplyAt)
+		assert.JSONEq(s.T(), `{"price":90}`, string(changes[0].Change.Content))
+		assert.Equal(s.T(), futureID, changes[1].ID)
+	})
+
+	s.Run("claim only due changes once", func() {
+		changes, err := changeRepo.ClaimDueScheduledChanges(ctx, 10, time.Minute)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), changes, 1)
+		assert.Equal(s.T(), dueID, changes[0].ID)
+
+		changes, err = changeRepo.ClaimDueScheduledChanges(ctx, 10, time.Minute)
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), changes)
+	})
+
+	s.Run("finish and cancel", func() {
+		require.NoError(s.T(), changeRepo.FinishScheduledChange(ctx, dueID, models.ScheduledChangeApplied, ""))
+		assert.ErrorIs(s.T(), changeRepo.CancelScheduledChange(ctx, bannerID, dueID), errs.ErrNotFound)
+
+		require.NoError(s.T(), changeRepo.CancelScheduledChange(ctx, bannerID, futureID))
+		changes, err := changeRepo.ListScheduledChanges(ctx, bannerID)
+		require.NoError(s.T(), err)
+		assert.Empty(s.T(), changes)
+	})
+}
@@ -0,0 +1,48 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	"backend-trainee-assignment-2024/internal/repo"
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestEvents() {
+	eventRepo := repo.NewEventRepository(s.db)
+	ctx := context.Background()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.Add(-time.Hour)
+	event := func(eventType models.EventType, variant string, createdAt time.Time) models.Event {
+		return models.Event{Type: eventType, BannerID: 7001, Variant: variant, TagID: 1, UserID: 42, CreatedAt: createdAt}
+	}
+
+	err := eventRepo.InsertEvents(ctx, []models.Event{
+		event(models.EventImpression, "a", yesterday),
+		event(models.EventImpression, "a", today),
+		event(models.EventImpression, "a", today),
+		event(models.EventClick, "a", today),
+		event(models.EventImpression, "b", today),
+		event(models.EventImpression, "b", today.Add(-48*time.Hour)),
+	})
+	require.NoError(s.T(), err)
+
+	stats, err := eventRepo.GetBannerStats(ctx, 7001, today.AddDate(0, 0, -1))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), stats, 2)
+
+	assert.Equal(s.T(), yesterday.Format(time.DateOnly), stats[0].Day)
+	assert.Equal(s.T(), int64(1), stats[0].Impressions)
+
+	assert.Equal(s.T(), today.Format(time.DateOnly), stats[1].Day)
+	assert.Equal(s.T(), int64(3), stats[1].Impressions)
+	assert.Equal(s.T(), int64(1), stats[1].Clicks)
+	assert.InDelta(s.T(), 1.0/3, stats[1].CTR, 1e-9)
+	require.Len(s.T(), stats[1].Variants, 2)
+	assert.Equal(s.T(), "a", stats[1].Variants[0].Variant)
+	assert.InDelta(s.T(), 0.5, stats[1].Variants[0].CTR, 1e-9)
+	assert.Equal(s.T(), int64(1), stats[1].Variants[1].Impressions)
+}
@@ -0,0 +1,44 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestFrequencyCap() {
+	ctx := context.Background()
+	frequencyCap := &models.FrequencyCap{Count: 3, Window: 86400}
+
+	bannerID, err := s.repo.CreateBanner(ctx, models.BannerInput{TagIDs: []int64{5301}, FeatureID: 6301, Content: json.RawMessage(`{}`), IsActive: true, FrequencyCap: frequencyCap})
+	require.NoError(s.T(), err)
+
+	s.Run("cap is stored", func() {
+		banner, err := s.repo.GetBanner(ctx, 5301, 6301, true)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), frequencyCap, banner.FrequencyCap)
+	})
+
+	s.Run("update without cap keeps it", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{Content: json.RawMessage(`{"title":"new"}`)})
+		require.NoError(s.T(), err)
+
+		banner, err := s.repo.GetBannerByID(ctx, bannerID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), frequencyCap, banner.FrequencyCap)
+	})
+
+	s.Run("zero cap removes it", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{FrequencyCap: &models.FrequencyCap{}})
+		require.NoError(s.T(), err)
+
+		banners, err := s.repo.ListBanners(ctx, n.NullInt64From(6301), n.NullInt64{}, n.NullUint64{}, n.NullUint64{})
+		require.NoError(s.T(), err)
+		require.Len(s.T(), banners, 1)
+		assert.Nil(s.T(), banners[0].FrequencyCap)
+	})
+}
@@ -0,0 +1,56 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestVariants() {
+	ctx := context.Background()
+	variants := []models.BannerVariant{
+		{Name: "b", Content: json.RawMessage(`{"title":"b"}`), Weight: 3},
+		{Name: "a", Content: json.RawMessage(`{"title":"a"}`), Weight: 1},
+	}
+
+	bannerID, err := s.repo.CreateBanner(ctx, models.BannerInput{TagIDs: []int64{5201}, FeatureID: 6201, Content: json.RawMessage(`{"title":"base"}`), IsActive: true, Variants: variants})
+	require.NoError(s.T(), err)
+
+	s.Run("variants are stored ordered by name", func() {
+		banner, err := s.repo.GetBanner(ctx, 5201, 6201, true)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), banner.Variants, 2)
+		assert.Equal(s.T(), "a", banner.Variants[0].Name)
+		assert.JSONEq(s.T(), `{"title":"a"}`, string(banner.Variants[0].Content))
+		assert.Equal(s.T(), 3, banner.Variants[1].Weight)
+	})
+
+	s.Run("update without variants keeps them", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{Content: json.RawMessage(`{"title":"new"}`)})
+		require.NoError(s.T(), err)
+
+		banner, err := s.repo.GetBannerByID(ctx, bannerID)
+		require.NoError(s.T(), err)
+		assert.Len(s.T(), banner.Variants, 2)
+	})
+
+	s.Run("variants are replaced", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{Variants: []models.BannerVariant{{Name: "c", Content: json.RawMessage(`{}`), Weight: 1}}})
+		require.NoError(s.T(), err)
+
+		banner, err := s.repo.GetBannerByID(ctx, bannerID)
+		require.NoError(s.T(), err)
+		require.Len(s.T(), banner.Variants, 1)
+		assert.Equal(s.T(), "c", banner.Variants[0].Name)
+	})
+
+	s.Run("negative weight is rejected", func() {
+		_, err := s.repo.UpdateBanner(ctx, bannerID, n.NullInt64{}, models.BannerPatch{Variants: []models.BannerVariant{{Name: "d", Content: json.RawMessage(`{}`), Weight: -1}}})
+		assert.ErrorIs(s.T(), err, errs.ErrInvalidVariants)
+	})
+}
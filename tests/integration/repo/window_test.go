@@ -0,0 +1,57 @@
+package repo_test
+
+import (
+	"backend-trainee-assignment-2024/internal/errs"
+	"backend-trainee-assignment-2024/internal/models"
+	n "backend-trainee-assignment-2024/internal/nullable"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *BannerRepositoryTestSuite) TestActiveWindow() {
+	now := time.Now()
+	window := models.ActiveWindow{
+		ActiveFrom:  n.NullInt64From(now.Add(time.Hour).Unix()),
+		ActiveUntil: n.NullInt64From(now.Add(2 * time.Hour).Unix()),
+	}
+
+	bannerID, err := s.repo.CreateBanner(context.Background(), models.BannerInput{TagIDs: []int64{5001}, FeatureID: 6001, Content: json.RawMessage(`{"title":"campaign"}`), IsActive: true, ActiveWindow: window})
+	require.NoError(s.T(), err)
+
+	s.Run("banner before window is hidden from users", func() {
+		_, err := s.repo.GetBanner(context.Background(), 5001, 6001, true)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+
+		banner, err := s.repo.GetBanner(context.Background(), 5001, 6001, false)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), bannerID, banner.ID)
+		assert.Equal(s.T(), window, banner.ActiveWindow)
+	})
+
+	s.Run("cleared start opens window", func() {
+		_, err := s.repo.UpdateBanner(context.Background(), bannerID, n.NullInt64{}, models.BannerPatch{ActiveWindow: models.ActiveWindow{ActiveFrom: n.NullInt64From(0)}})
+		require.NoError(s.T(), err)
+
+		banner, err := s.repo.GetBanner(context.Background(), 5001, 6001, true)
+		require.NoError(s.T(), err)
+		assert.False(s.T(), banner.ActiveFrom.Valid)
+		assert.Equal(s.T(), window.ActiveUntil, banner.ActiveUntil)
+	})
+
+	s.Run("end before start is rejected", func() {
+		_, err := s.repo.UpdateBanner(context.Background(), bannerID, n.NullInt64{}, models.BannerPatch{ActiveWindow: models.ActiveWindow{ActiveFrom: n.NullInt64From(now.Add(3 * time.Hour).Unix())}})
+		assert.ErrorIs(s.T(), err, errs.ErrInvalidValue)
+	})
+
+	s.Run("banner after window is hidden from users", func() {
+		_, err := s.repo.UpdateBanner(context.Background(), bannerID, n.NullInt64{}, models.BannerPatch{ActiveWindow: models.ActiveWindow{ActiveUntil: n.NullInt64From(now.Add(-time.Minute).Unix())}})
+		require.NoError(s.T(), err)
+
+		_, err = s.repo.GetBanner(context.Background(), 5001, 6001, true)
+		assert.ErrorIs(s.T(), err, errs.ErrNotFound)
+	})
+}